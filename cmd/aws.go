@@ -6,6 +6,9 @@ import (
 	"strings"
 
 	"github.com/devops-chris/cloudctx/internal/aws"
+	"github.com/devops-chris/cloudctx/internal/config"
+	"github.com/devops-chris/cloudctx/internal/favorites"
+	"github.com/devops-chris/cloudctx/internal/history"
 	"github.com/devops-chris/cloudctx/internal/provider"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -17,15 +20,21 @@ var awsCmd = &cobra.Command{
 	Long: `Manage AWS profiles and SSO authentication.
 
 Without arguments, opens an interactive profile picker.
-With a profile name, sets that profile directly.
+With a profile name, sets that profile directly. An account ID or an ARN
+(e.g. from a log line) selects the profiles for that account and role.
 
 Examples:
   cloudctx aws                    # Interactive picker
   cloudctx aws my-account:admin   # Set specific profile
+  cloudctx aws 123456789012       # Pick from the account's profiles
+  cloudctx aws -                  # Switch back to the previous profile
   cloudctx aws -c                 # Show current profile
-  cloudctx aws -l                 # List all profiles`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runAWS,
+  cloudctx aws -l                 # List all profiles
+  cloudctx aws --favorites        # Pick from favorite profiles
+  cloudctx aws prod -g            # Set [default] with shell integration loaded`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeAWSProfiles,
+	RunE:              runAWS,
 }
 
 var (
@@ -33,6 +42,8 @@ var (
 	awsShowList    bool
 	awsSSOOnly     bool
 	awsManualOnly  bool
+	awsFavorites   bool
+	awsGlobal      bool
 )
 
 func init() {
@@ -42,10 +53,143 @@ func init() {
 	awsCmd.Flags().BoolVarP(&awsShowList, "list", "l", false, "list all profiles")
 	awsCmd.Flags().BoolVar(&awsSSOOnly, "sso", false, "show only SSO-synced profiles")
 	awsCmd.Flags().BoolVar(&awsManualOnly, "manual", false, "show only manually created profiles")
+	awsCmd.Flags().BoolVar(&awsFavorites, "favorites", false, "show only favorite profiles")
+	awsCmd.Flags().BoolVarP(&awsGlobal, "global", "g", false, "set [default] even with shell integration loaded")
+}
+
+// newAWSProvider creates an AWS provider for the default SSO instance
+func newAWSProvider() *aws.Provider {
+	instances := cfg.AWS.SSOInstances()
+	if len(instances) == 0 {
+		// Not configured yet; commands report this themselves
+		return newAWSInstanceProvider(config.SSOInstance{SSORegion: cfg.AWS.SSORegion})
+	}
+	return newAWSInstanceProvider(instances[0])
+}
+
+// awsProviders creates a provider for the named SSO instance, or for every
+// configured instance when name is empty
+func awsProviders(name string) ([]*aws.Provider, error) {
+	if err := cfg.AWS.ValidateInstances(); err != nil {
+		return nil, err
+	}
+
+	instances := cfg.AWS.SSOInstances()
+	if name == "" {
+		if len(instances) == 0 {
+			return []*aws.Provider{newAWSProvider()}, nil
+		}
+		providers := make([]*aws.Provider, 0, len(instances))
+		for _, instance := range instances {
+			providers = append(providers, newAWSInstanceProvider(instance))
+		}
+		return providers, nil
+	}
+
+	var names []string
+	for _, instance := range instances {
+		if instance.Name == name {
+			return []*aws.Provider{newAWSInstanceProvider(instance)}, nil
+		}
+		if instance.Name != "" {
+			names = append(names, instance.Name)
+		}
+	}
+	return nil, fmt.Errorf("SSO instance '%s' not found (configured: %s)", name, strings.Join(names, ", "))
+}
+
+// awsSyncProviders returns the providers to sync: the named SSO instance or
+// "organizations", or every configured SSO instance plus Organizations sync
+// when name is empty
+func awsSyncProviders(name string) ([]*aws.Provider, error) {
+	org := cfg.AWS.Organizations
+	if name == "organizations" && org.SourceProfile != "" {
+		return []*aws.Provider{newAWSOrganizationsProvider(org)}, nil
+	}
+	if name == "" && len(cfg.AWS.SSOInstances()) == 0 && org.SourceProfile != "" {
+		return []*aws.Provider{newAWSOrganizationsProvider(org)}, nil
+	}
+
+	providers, err := awsProviders(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" && org.SourceProfile != "" {
+		providers = append(providers, newAWSOrganizationsProvider(org))
+	}
+	return providers, nil
+}
+
+// newAWSInstanceProvider creates an AWS provider for one SSO instance
+func newAWSInstanceProvider(instance config.SSOInstance) *aws.Provider {
+	return aws.NewProvider(instance.SSOStartURL, instance.SSORegion, cfg.AWS.DefaultRegion).
+		WithInstance(instance.Name, instance.ProfilePrefix).
+		WithSyncOptions(awsSyncOptions())
+}
+
+// newAWSOrganizationsProvider creates an AWS provider that syncs from AWS Organizations
+func newAWSOrganizationsProvider(org config.OrganizationsConfig) *aws.Provider {
+	return aws.NewProvider("", cfg.AWS.SSORegion, cfg.AWS.DefaultRegion).
+		WithOrganizations(org.SourceProfile, org.RoleName, org.ProfilePrefix).
+		WithSyncOptions(awsSyncOptions())
+}
+
+// awsSyncOptions returns the sync options from the config file
+func awsSyncOptions() aws.SyncOptions {
+	credentialProcess := ""
+	if cfg.AWS.CredentialProcess {
+		credentialProcess = "cloudctx"
+	}
+	return aws.SyncOptions{
+		Concurrency:         cfg.AWS.SyncConcurrency,
+		ProfileNameTemplate: cfg.AWS.ProfileNameTemplate,
+		Include:             filterRules(cfg.AWS.Include),
+		Exclude:             filterRules(cfg.AWS.Exclude),
+		Settings:            profileSettings(cfg.AWS.ProfileSettings),
+		PreserveRegion:      cfg.AWS.PreserveRegion,
+		ChainedRoles:        chainedRoles(cfg.AWS.ChainedRoles),
+		CredentialProcess:   credentialProcess,
+	}
+}
+
+// filterRules converts sync filters from the config file
+func filterRules(filters []config.SyncFilter) []aws.FilterRule {
+	var rules []aws.FilterRule
+	for _, f := range filters {
+		rules = append(rules, aws.FilterRule{Account: f.Account, AccountID: f.AccountID, Role: f.Role})
+	}
+	return rules
+}
+
+// profileSettings converts region/output mappings from the config file
+func profileSettings(settings []config.ProfileSetting) []aws.ProfileSetting {
+	var converted []aws.ProfileSetting
+	for _, s := range settings {
+		converted = append(converted, aws.ProfileSetting{
+			Match:  aws.FilterRule{Account: s.Account, AccountID: s.AccountID, Role: s.Role},
+			Region: s.Region,
+			Output: s.Output,
+		})
+	}
+	return converted
+}
+
+// chainedRoles converts role-chaining definitions from the config file
+func chainedRoles(chains []config.ChainedRole) []aws.ChainedRole {
+	var converted []aws.ChainedRole
+	for _, c := range chains {
+		converted = append(converted, aws.ChainedRole{
+			Match:      aws.FilterRule{Account: c.Account, AccountID: c.AccountID, Role: c.Role},
+			Name:       c.Name,
+			RoleARN:    c.RoleARN,
+			ExternalID: c.ExternalID,
+		})
+	}
+	return converted
 }
 
 func runAWS(cmd *cobra.Command, args []string) error {
-	p := aws.NewProvider(cfg.AWS.SSOStartURL, cfg.AWS.SSORegion, cfg.AWS.DefaultRegion)
+	p := newAWSProvider()
 
 	// Show current profile
 	if awsShowCurrent {
@@ -57,6 +201,11 @@ func runAWS(cmd *cobra.Command, args []string) error {
 		return listAWS(p)
 	}
 
+	// Switch back to the previous profile
+	if len(args) == 1 && args[0] == "-" {
+		return previousAWS(p)
+	}
+
 	// Set specific profile
 	if len(args) == 1 {
 		return setAWS(p, args[0])
@@ -82,15 +231,25 @@ func showCurrentAWS(p *aws.Provider) error {
 	return nil
 }
 
-// filterContexts applies the --sso and --manual flags
+// filterContexts applies the --sso, --manual and --favorites flags
 func filterContexts(contexts []provider.Context) []provider.Context {
-	if !awsSSOOnly && !awsManualOnly {
+	if !awsSSOOnly && !awsManualOnly && !awsFavorites {
 		return contexts // No filter
 	}
 
+	var favs map[string]bool
+	if awsFavorites {
+		favs = favorites.Set("aws")
+	}
+
 	var filtered []provider.Context
 	for _, ctx := range contexts {
-		if awsSSOOnly && ctx.Managed {
+		if awsFavorites && !favs[ctx.Name] {
+			continue
+		}
+		if !awsSSOOnly && !awsManualOnly {
+			filtered = append(filtered, ctx)
+		} else if awsSSOOnly && ctx.Managed {
 			filtered = append(filtered, ctx)
 		} else if awsManualOnly && !ctx.Managed {
 			filtered = append(filtered, ctx)
@@ -99,6 +258,18 @@ func filterContexts(contexts []provider.Context) []provider.Context {
 	return filtered
 }
 
+// warnNoAWSProfiles explains an empty profile list under the current filters
+func warnNoAWSProfiles() {
+	pterm.Warning.Println("No AWS profiles found")
+	if awsFavorites {
+		pterm.FgGray.Println("Add favorites with 'cloudctx fav add <profile>'")
+	} else if awsManualOnly && !awsSSOOnly {
+		pterm.FgGray.Println("No manually created profiles found")
+	} else {
+		pterm.FgGray.Println("Run 'cloudctx aws sync' to fetch profiles from SSO")
+	}
+}
+
 func listAWS(p *aws.Provider) error {
 	contexts, err := p.ListContexts()
 	if err != nil {
@@ -108,14 +279,7 @@ func listAWS(p *aws.Provider) error {
 	contexts = filterContexts(contexts)
 
 	if len(contexts) == 0 {
-		pterm.Warning.Println("No AWS profiles found")
-		if awsSSOOnly {
-			pterm.FgGray.Println("Run 'cloudctx aws sync' to fetch profiles from SSO")
-		} else if awsManualOnly {
-			pterm.FgGray.Println("No manually created profiles found")
-		} else {
-			pterm.FgGray.Println("Run 'cloudctx aws sync' to fetch profiles from SSO")
-		}
+		warnNoAWSProfiles()
 		return nil
 	}
 
@@ -124,9 +288,15 @@ func listAWS(p *aws.Provider) error {
 		WithTextStyle(pterm.NewStyle(pterm.FgLightWhite)).
 		Println("AWS Profiles")
 
-	tableData := pterm.TableData{
-		{"", "Profile", "Account ID", "Role", "Region", "Source"},
+	// Health from the last 'cloudctx aws verify', if it was ever run
+	health, _ := p.VerifyResults()
+	favs := favorites.Set("aws")
+
+	header := []string{"", "Profile", "Account", "Account ID", "Role", "Region", "Type"}
+	if len(health) > 0 {
+		header = append(header, "Health")
 	}
+	tableData := pterm.TableData{header}
 
 	for _, ctx := range contexts {
 		marker := " "
@@ -135,18 +305,33 @@ func listAWS(p *aws.Provider) error {
 			marker = "*"
 			name = pterm.FgGreen.Sprint(ctx.Name)
 		}
-		source := pterm.FgYellow.Sprint("manual")
+		marker += favoriteMarker(favs[ctx.Name])
+		// Profiles managed by cloudctx in cyan, manual ones in yellow
+		profileType := pterm.FgYellow.Sprint(ctx.Type)
 		if ctx.Managed {
-			source = pterm.FgCyan.Sprint("sso")
+			profileType = pterm.FgCyan.Sprint(ctx.Type)
+		}
+		role := ctx.Role
+		if len(ctx.Chain) > 0 {
+			role += pterm.FgGray.Sprintf(" via %s", strings.Join(ctx.Chain, " → "))
 		}
-		tableData = append(tableData, []string{
+		row := []string{
 			marker,
 			name,
+			ctx.AccountName,
 			ctx.AccountID,
-			ctx.Role,
+			role,
 			ctx.Region,
-			source,
-		})
+			profileType,
+		}
+		if len(health) > 0 {
+			status := pterm.FgGray.Sprint("-")
+			if result, ok := health[ctx.Name]; ok {
+				status = verifyStatusStyle(result.Status)
+			}
+			row = append(row, status)
+		}
+		tableData = append(tableData, row)
 	}
 
 	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
@@ -157,6 +342,9 @@ func listAWS(p *aws.Provider) error {
 	} else if awsManualOnly {
 		filterNote = " (manual only)"
 	}
+	if awsFavorites {
+		filterNote += " (favorites)"
+	}
 	fmt.Printf("\nTotal: %d profile(s)%s\n\n", len(contexts), filterNote)
 
 	return nil
@@ -168,18 +356,7 @@ func setAWS(p *aws.Provider, name string) error {
 		return err
 	}
 
-	// Find matching profiles
-	var matches []provider.Context
-	for _, ctx := range contexts {
-		if ctx.Name == name {
-			// Exact match
-			matches = []provider.Context{ctx}
-			break
-		}
-		if strings.Contains(ctx.Name, name) {
-			matches = append(matches, ctx)
-		}
-	}
+	matches := matchProfiles(contexts, name)
 
 	if len(matches) == 0 {
 		pterm.Error.Printf("No profile matching '%s'\n", name)
@@ -195,6 +372,62 @@ func setAWS(p *aws.Provider, name string) error {
 	return selectProfile(p, matches[0].Name)
 }
 
+// matchProfiles finds the profiles for a name: an exact profile name, the
+// profiles of an account ID or ARN (narrowed to the ARN's role when a
+// profile has it), or profiles whose name contains it
+func matchProfiles(contexts []provider.Context, name string) []provider.Context {
+	for _, ctx := range contexts {
+		if ctx.Name == name {
+			return []provider.Context{ctx}
+		}
+	}
+
+	if query, ok := aws.ParseAccountQuery(name); ok {
+		accountMatches, roleMatches := matchAccount(contexts, query)
+		if len(roleMatches) > 0 {
+			return roleMatches
+		}
+		return accountMatches
+	}
+
+	var matches []provider.Context
+	for _, ctx := range contexts {
+		if strings.Contains(ctx.Name, name) {
+			matches = append(matches, ctx)
+		}
+	}
+	return matches
+}
+
+// matchAccount returns the profiles for the queried account, and those of
+// them that use the queried role
+func matchAccount(contexts []provider.Context, query aws.AccountQuery) (accountMatches, roleMatches []provider.Context) {
+	for _, ctx := range contexts {
+		if !query.MatchesAccount(ctx) {
+			continue
+		}
+		accountMatches = append(accountMatches, ctx)
+		if query.MatchesRole(ctx) {
+			roleMatches = append(roleMatches, ctx)
+		}
+	}
+	return accountMatches, roleMatches
+}
+
+// previousAWS switches to the profile that was active before the last switch
+func previousAWS(p *aws.Provider) error {
+	previous, err := history.Previous("aws")
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		pterm.Warning.Println("No previous AWS profile")
+		pterm.FgGray.Println("Switch profiles with 'cloudctx aws <profile>' first")
+		return nil
+	}
+	return selectProfile(p, previous.Name)
+}
+
 func interactiveAWS(p *aws.Provider) error {
 	contexts, err := p.ListContexts()
 	if err != nil {
@@ -204,14 +437,7 @@ func interactiveAWS(p *aws.Provider) error {
 	contexts = filterContexts(contexts)
 
 	if len(contexts) == 0 {
-		pterm.Warning.Println("No AWS profiles found")
-		if awsSSOOnly {
-			pterm.FgGray.Println("Run 'cloudctx aws sync' to fetch profiles from SSO")
-		} else if awsManualOnly {
-			pterm.FgGray.Println("No manually created profiles found")
-		} else {
-			pterm.FgGray.Println("Run 'cloudctx aws sync' to fetch profiles from SSO")
-		}
+		warnNoAWSProfiles()
 		return nil
 	}
 
@@ -226,18 +452,21 @@ func pickFromMatches(p *aws.Provider, contexts []provider.Context) error {
 		currentName = current.Name
 	}
 
-	// Build options with source indicator
+	// Favorites first
+	favs := favorites.Set("aws")
+	contexts = favorites.Pin(contexts, favs)
+
+	// Build options with account name and profile type
 	options := make([]string, len(contexts))
+	profileNames := make(map[string]string, len(contexts))
 	for i, ctx := range contexts {
-		source := "[manual]"
-		if ctx.Managed {
-			source = "[sso]"
-		}
+		marker := " "
 		if ctx.Name == currentName {
-			options[i] = fmt.Sprintf("* %-50s %s", ctx.Name, source)
-		} else {
-			options[i] = fmt.Sprintf("  %-50s %s", ctx.Name, source)
+			marker = "*"
 		}
+		marker += favoriteMarker(favs[ctx.Name])
+		options[i] = fmt.Sprintf("%s %-50s %-30s [%s]", marker, ctx.Name, ctx.AccountName, ctx.Type)
+		profileNames[options[i]] = ctx.Name
 	}
 
 	fmt.Println()
@@ -255,23 +484,30 @@ func pickFromMatches(p *aws.Provider, contexts []provider.Context) error {
 		return nil // User cancelled
 	}
 
-	// Extract profile name (remove marker and source tag)
-	profileName := strings.TrimPrefix(selected, "* ")
-	profileName = strings.TrimPrefix(profileName, "  ")
-	// Remove the source tag and trailing whitespace
-	if idx := strings.Index(profileName, " ["); idx != -1 {
-		profileName = strings.TrimSpace(profileName[:idx])
-	}
-
-	return selectProfile(p, profileName)
+	return selectProfile(p, profileNames[selected])
 }
 
 func selectProfile(p *aws.Provider, name string) error {
+	previous := currentContextName(p)
+
+	// With shell integration, only the calling shell switches
+	if shellMode() && !awsGlobal {
+		if err := exportToShell(awsCredentialEnv, []envVar{{"AWS_PROFILE", name}}); err != nil {
+			pterm.Error.Printf("Failed to set profile: %v\n", err)
+			return err
+		}
+		_ = history.Record("aws", previous, name)
+		fmt.Println()
+		pterm.Success.Printf("Switched to %s in this shell\n", pterm.FgCyan.Sprint(name))
+		return nil
+	}
+
 	// Update ~/.aws/config [default] section
 	if err := p.SetContext(name); err != nil {
 		pterm.Error.Printf("Failed to set profile: %v\n", err)
 		return err
 	}
+	_ = history.Record("aws", previous, name)
 
 	fmt.Println()
 	pterm.Success.Printf("Switched to %s\n", pterm.FgCyan.Sprint(name))
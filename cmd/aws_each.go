@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"github.com/spf13/cobra"
+)
+
+var awsEachCmd = &cobra.Command{
+	Use:   "each [--filter pattern] -- <command> [args...]",
+	Short: "Run a command in every matching AWS profile",
+	Long: `Run a command once per AWS profile, with AWS_PROFILE set for each run.
+
+The current profile is not changed. Output lines are prefixed with the
+profile name (or grouped per profile with --group), and a summary of exit
+codes is printed at the end. The command fails if any run fails.
+
+The filter matches profile names, account names and account IDs: a glob
+when it contains * or ?, otherwise a substring.
+
+Examples:
+  cloudctx aws each --filter 'prod-*' -- aws s3 ls
+  cloudctx aws each --filter readonly --parallel 8 -- aws sts get-caller-identity
+  cloudctx aws each --sso --group -- sh -c 'aws ec2 describe-vpcs | jq length'`,
+	RunE: runAWSEach,
+}
+
+var awsEachOptions eachOptions
+
+func init() {
+	awsCmd.AddCommand(awsEachCmd)
+	awsEachCmd.Flags().StringVarP(&awsEachOptions.filter, "filter", "f", "", "only profiles matching this pattern")
+	awsEachCmd.Flags().IntVarP(&awsEachOptions.parallel, "parallel", "p", 4, "number of commands to run at once")
+	awsEachCmd.Flags().BoolVarP(&awsEachOptions.group, "group", "g", false, "print each profile's output as one block when it finishes")
+	awsEachCmd.Flags().BoolVar(&awsSSOOnly, "sso", false, "only SSO-synced profiles")
+	awsEachCmd.Flags().BoolVar(&awsManualOnly, "manual", false, "only manually created profiles")
+	awsEachCmd.Flags().BoolVar(&awsFavorites, "favorites", false, "only favorite profiles")
+}
+
+// awsCredentialEnv are variables that would take precedence over AWS_PROFILE
+var awsCredentialEnv = []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"}
+
+func runAWSEach(cmd *cobra.Command, args []string) error {
+	command, err := eachCommand(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	contexts, err := newAWSProvider().ListContexts()
+	if err != nil {
+		return err
+	}
+
+	contexts = filterEachContexts(filterContexts(contexts), awsEachOptions.filter)
+
+	return runEach(contexts, awsEachOptions, command, func(ctx provider.Context) []string {
+		return eachEnv(awsCredentialEnv, "AWS_PROFILE="+ctx.Name)
+	})
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/devops-chris/cloudctx/internal/aws"
+	"github.com/spf13/cobra"
+)
+
+var awsEnvCmd = &cobra.Command{
+	Use:   "env [profile]",
+	Short: "Print credentials for a profile as environment variables",
+	Long: `Resolve a profile into credentials and print them as environment variables.
+
+SSO profiles are exchanged for temporary role credentials, role_arn profiles
+assume their role, and static keys are printed as they are. Useful for tools
+that don't understand sso_session profiles, such as docker-compose or older SDKs.
+
+Without a profile, the current profile is used. The format defaults to your shell.
+
+Examples:
+  eval "$(cloudctx aws env prod:admin)"
+  cloudctx aws env prod:admin --format fish | source
+  cloudctx aws env prod:admin --format powershell | Invoke-Expression
+  cloudctx aws env prod:admin --format dotenv > .env`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeAWSProfiles,
+	RunE:              runAWSEnv,
+}
+
+var awsEnvFormat string
+
+func init() {
+	awsCmd.AddCommand(awsEnvCmd)
+	awsEnvCmd.Flags().StringVarP(&awsEnvFormat, "format", "f", "", "output format: bash, zsh, fish, powershell or dotenv (default: detect from shell)")
+}
+
+func runAWSEnv(cmd *cobra.Command, args []string) error {
+	format := awsEnvFormat
+	if format == "" {
+		format = detectShell()
+	}
+
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	}
+
+	creds, err := newAWSProvider().ResolveCredentials(name)
+	if err != nil {
+		// stdout is eval'd by the shell, so the error goes to stderr only
+		return fmt.Errorf("failed to resolve credentials: %w", err)
+	}
+
+	output, err := formatEnv(format, credentialsEnv(creds))
+	if err != nil {
+		return err
+	}
+	fmt.Print(output)
+	return nil
+}
+
+// envVar is an environment variable to print
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// credentialsEnv lists the environment variables for resolved credentials
+func credentialsEnv(creds *aws.Credentials) []envVar {
+	vars := []envVar{
+		{"AWS_ACCESS_KEY_ID", creds.AccessKeyID},
+		{"AWS_SECRET_ACCESS_KEY", creds.SecretAccessKey},
+	}
+	if creds.SessionToken != "" {
+		vars = append(vars, envVar{"AWS_SESSION_TOKEN", creds.SessionToken})
+	}
+	if !creds.Expiration.IsZero() {
+		vars = append(vars, envVar{"AWS_CREDENTIAL_EXPIRATION", creds.Expiration.UTC().Format(time.RFC3339)})
+	}
+	if creds.Region != "" {
+		vars = append(vars, envVar{"AWS_REGION", creds.Region}, envVar{"AWS_DEFAULT_REGION", creds.Region})
+	}
+	return vars
+}
+
+// formatEnv renders environment variables for a shell or a dotenv file
+func formatEnv(format string, vars []envVar) (string, error) {
+	var b strings.Builder
+	for _, v := range vars {
+		switch format {
+		case "bash", "zsh", "sh":
+			fmt.Fprintf(&b, "export %s=%s\n", v.Name, quotePOSIX(v.Value))
+		case "fish":
+			fmt.Fprintf(&b, "set -gx %s %s;\n", v.Name, quoteFish(v.Value))
+		case "powershell", "pwsh":
+			fmt.Fprintf(&b, "$Env:%s = '%s'\n", v.Name, strings.ReplaceAll(v.Value, "'", "''"))
+		case "dotenv":
+			fmt.Fprintf(&b, "%s=%s\n", v.Name, v.Value)
+		default:
+			return "", fmt.Errorf("unknown format '%s' (use bash, zsh, fish, powershell or dotenv)", format)
+		}
+	}
+	return b.String(), nil
+}
+
+// detectShell guesses the output format from the user's shell
+func detectShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	switch filepath.Base(os.Getenv("SHELL")) {
+	case "fish":
+		return "fish"
+	case "zsh":
+		return "zsh"
+	default:
+		return "bash"
+	}
+}
+
+// quotePOSIX single-quotes a value for sh-compatible shells
+func quotePOSIX(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quoteFish single-quotes a value for fish
+func quoteFish(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import "testing"
+
+func TestFormatEnv(t *testing.T) {
+	vars := []envVar{{"AWS_ACCESS_KEY_ID", "AKID"}, {"AWS_SESSION_TOKEN", `it's\x`}}
+
+	tests := map[string]string{
+		"bash":       "export AWS_ACCESS_KEY_ID='AKID'\nexport AWS_SESSION_TOKEN='it'\\''s\\x'\n",
+		"fish":       "set -gx AWS_ACCESS_KEY_ID 'AKID';\nset -gx AWS_SESSION_TOKEN 'it\\'s\\\\x';\n",
+		"powershell": "$Env:AWS_ACCESS_KEY_ID = 'AKID'\n$Env:AWS_SESSION_TOKEN = 'it''s\\x'\n",
+		"dotenv":     "AWS_ACCESS_KEY_ID=AKID\nAWS_SESSION_TOKEN=it's\\x\n",
+	}
+	for format, want := range tests {
+		got, err := formatEnv(format, vars)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if got != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", format, got, want)
+		}
+	}
+
+	if _, err := formatEnv("cmd", vars); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -16,5 +16,6 @@ var awsListCmd = &cobra.Command{
 
 func init() {
 	awsCmd.AddCommand(awsListCmd)
+	awsListCmd.Flags().BoolVar(&awsFavorites, "favorites", false, "show only favorite profiles")
 }
 
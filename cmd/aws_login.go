@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/devops-chris/cloudctx/internal/aws"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -17,30 +16,43 @@ Opens your browser to complete the SSO authentication flow.
 After login, your SSO credentials will be cached for subsequent commands.
 
 Examples:
-  cloudctx aws login`,
+  cloudctx aws login                    # Log in to every SSO instance
+  cloudctx aws login --instance acme    # Log in to one SSO instance`,
 	RunE: runAWSLogin,
 }
 
+var awsLoginInstance string
+
 func init() {
 	awsCmd.AddCommand(awsLoginCmd)
+	awsLoginCmd.Flags().StringVar(&awsLoginInstance, "instance", "", "SSO instance to log in to (default: all)")
 }
 
 func runAWSLogin(cmd *cobra.Command, args []string) error {
-	p := aws.NewProvider(cfg.AWS.SSOStartURL, cfg.AWS.SSORegion, cfg.AWS.DefaultRegion)
-
-	pterm.Info.Println("Opening browser for AWS SSO login...")
-	pterm.FgGray.Println("Complete the authentication in your browser")
-	fmt.Println()
-
-	err := p.Login()
+	providers, err := awsProviders(awsLoginInstance)
 	if err != nil {
-		pterm.Error.Println("Login failed")
 		return err
 	}
 
-	pterm.Success.Println("Successfully logged in to AWS SSO")
+	for _, p := range providers {
+		if name := p.InstanceName(); name != "" {
+			pterm.Info.Printf("Opening browser for AWS SSO login (%s)...\n", pterm.FgCyan.Sprint(name))
+		} else {
+			pterm.Info.Println("Opening browser for AWS SSO login...")
+		}
+		pterm.FgGray.Println("Complete the authentication in your browser")
+		fmt.Println()
+
+		if err := p.Login(); err != nil {
+			pterm.Error.Println("Login failed")
+			return err
+		}
+
+		pterm.Success.Println("Successfully logged in to AWS SSO")
+		fmt.Println()
+	}
+
 	pterm.FgGray.Println("Run 'cloudctx aws sync' to update your profiles")
 
 	return nil
 }
-
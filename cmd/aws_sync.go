@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/devops-chris/cloudctx/internal/aws"
 	"github.com/pterm/pterm"
@@ -14,21 +16,45 @@ var awsSyncCmd = &cobra.Command{
 	Long: `Synchronize AWS profiles from your SSO portal.
 
 This command fetches all accounts and roles you have access to via SSO
-and creates/updates AWS CLI profiles in ~/.aws/config.
+and creates/updates AWS CLI profiles in ~/.aws/config. With several SSO
+instances configured, each one is synced in turn.
+
+With aws.organizations configured, member accounts are also listed from AWS
+Organizations and get profiles that assume a role (OrganizationAccountAccessRole
+by default) from your IAM user's profile.
 
 Requires a valid SSO session - run 'cloudctx aws login' first if needed.
 
 Examples:
-  cloudctx aws sync`,
+  cloudctx aws sync
+  cloudctx aws sync --diff      # Show what changes, then sync
+  cloudctx aws sync --dry-run   # Show what would change, don't save
+  cloudctx aws sync --exclude 'sandbox-*'
+  cloudctx aws sync --include 'account=prod-*,role=Admin*'
+  cloudctx aws sync --instance acme   # Sync one SSO instance
+  cloudctx aws sync --instance organizations   # Sync from AWS Organizations only`,
 	RunE: runAWSSync,
 }
 
+var (
+	awsSyncDryRun   bool
+	awsSyncDiff     bool
+	awsSyncInclude  []string
+	awsSyncExclude  []string
+	awsSyncInstance string
+)
+
 func init() {
 	awsCmd.AddCommand(awsSyncCmd)
+	awsSyncCmd.Flags().BoolVar(&awsSyncDryRun, "dry-run", false, "show the changes without saving ~/.aws/config")
+	awsSyncCmd.Flags().BoolVar(&awsSyncDiff, "diff", false, "show the changes to ~/.aws/config before saving")
+	awsSyncCmd.Flags().StringVar(&awsSyncInstance, "instance", "", "SSO instance (or \"organizations\") to sync (default: all)")
+	awsSyncCmd.Flags().StringArrayVar(&awsSyncInclude, "include", nil, "only sync matching accounts/roles (e.g. 'account=prod-*,role=Admin*')")
+	awsSyncCmd.Flags().StringArrayVar(&awsSyncExclude, "exclude", nil, "skip matching accounts/roles (e.g. 'sandbox-*' or 'account_id=1234*')")
 }
 
 func runAWSSync(cmd *cobra.Command, args []string) error {
-	if cfg.AWS.SSOStartURL == "" {
+	if len(cfg.AWS.SSOInstances()) == 0 && cfg.AWS.Organizations.SourceProfile == "" {
 		pterm.Error.Println("SSO Start URL not configured")
 		fmt.Println()
 		pterm.Info.Println("Configure it in ~/.config/cloudctx/config.yaml:")
@@ -39,32 +65,179 @@ func runAWSSync(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 		pterm.Info.Println("Or set environment variable:")
 		pterm.FgCyan.Println("  export CLOUDCTX_AWS_SSO_START_URL=https://your-org.awsapps.com/start")
+		fmt.Println()
+		pterm.FgGray.Println("Without IAM Identity Center, set aws.organizations.source_profile instead")
 		return nil
 	}
 
-	p := aws.NewProvider(cfg.AWS.SSOStartURL, cfg.AWS.SSORegion, cfg.AWS.DefaultRegion)
+	// Filters from flags add to those in the config file
+	syncOptions := awsSyncOptions()
+	for _, f := range awsSyncInclude {
+		rule, err := aws.ParseFilterRule(f)
+		if err != nil {
+			return err
+		}
+		syncOptions.Include = append(syncOptions.Include, rule)
+	}
+	for _, f := range awsSyncExclude {
+		rule, err := aws.ParseFilterRule(f)
+		if err != nil {
+			return err
+		}
+		syncOptions.Exclude = append(syncOptions.Exclude, rule)
+	}
+
+	providers, err := awsSyncProviders(awsSyncInstance)
+	if err != nil {
+		return err
+	}
 
-	spinner, _ := pterm.DefaultSpinner.Start("Syncing profiles from AWS SSO...")
+	// A failing instance doesn't stop the others from syncing
+	var failed []string
+	for _, p := range providers {
+		if err := syncAWSInstance(p.WithSyncOptions(syncOptions)); err != nil {
+			if len(providers) == 1 {
+				return err
+			}
+			pterm.Error.Println(err)
+			failed = append(failed, syncSource(p))
+		}
+	}
 
-	err := p.Sync()
+	if !awsSyncDryRun && len(failed) < len(providers) {
+		pterm.FgGray.Println("Run 'cloudctx aws' to select a profile")
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("sync failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// syncSource describes where a provider syncs profiles from
+func syncSource(p *aws.Provider) string {
+	if p.Organizations() {
+		return "AWS Organizations"
+	}
+	if name := p.InstanceName(); name != "" {
+		return fmt.Sprintf("AWS SSO (%s)", name)
+	}
+	return "AWS SSO"
+}
+
+// syncAWSInstance plans, previews and applies the sync of one SSO instance
+func syncAWSInstance(p *aws.Provider) error {
+	source := syncSource(p)
+
+	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Syncing profiles from %s...", source))
+
+	plan, err := p.PlanSync()
 	if err != nil {
 		spinner.Fail("Sync failed")
-		pterm.FgGray.Println("Try running 'cloudctx aws login' first")
+		if p.Organizations() {
+			pterm.FgGray.Println("Check the credentials of aws.organizations.source_profile")
+		} else {
+			pterm.FgGray.Println("Try running 'cloudctx aws login' first")
+		}
 		return err
 	}
 
-	_ = spinner.Stop()
+	if len(plan.Failed) > 0 {
+		spinner.Warning("Some accounts could not be synced")
+		printAccountErrors(plan.Failed)
+	} else {
+		_ = spinner.Stop()
+	}
 
-	// Show results
-	contexts, err := p.ListContexts()
+	for _, collision := range plan.Collisions {
+		pterm.Warning.Printf("Profile name %s is shared by %d profiles; using %s\n",
+			pterm.FgCyan.Sprint(collision.Name), len(collision.Profiles), strings.Join(collision.Profiles, ", "))
+	}
+	for _, name := range plan.Conflicts {
+		pterm.Warning.Printf("Profile %s already exists and isn't managed by this sync; skipped\n", pterm.FgCyan.Sprint(name))
+	}
+
+	if awsSyncDryRun || awsSyncDiff {
+		diff, err := p.DiffSync(plan)
+		if err != nil {
+			return err
+		}
+		printSyncDiff(diff)
+	}
+
+	if awsSyncDryRun {
+		pterm.Info.Println("Dry run - ~/.aws/config was not changed")
+		fmt.Println()
+		return nil
+	}
+
+	result, err := p.ApplySync(plan)
 	if err != nil {
+		pterm.Error.Println("Failed to save profiles")
 		return err
 	}
 
-	pterm.Success.Printf("Synced %d profiles from AWS SSO\n", len(contexts))
+	// Show results
+	pterm.Success.Printf("Synced %d profiles from %d accounts in %s\n",
+		result.Profiles, len(result.Accounts), result.Duration.Round(time.Millisecond))
 	fmt.Println()
-	pterm.FgGray.Println("Run 'cloudctx aws' to select a profile")
 
 	return nil
 }
 
+// printSyncDiff shows the profiles a sync adds, removes and changes
+func printSyncDiff(diff *aws.SyncDiff) {
+	fmt.Println()
+	if diff.Empty() {
+		pterm.Info.Println("No changes to ~/.aws/config")
+		fmt.Println()
+		return
+	}
+
+	for _, change := range diff.Added {
+		pterm.FgGreen.Printf("+ [profile %s]\n", change.Name)
+		for _, key := range change.Keys {
+			pterm.FgGreen.Printf("    %s = %s\n", key.Key, key.New)
+		}
+	}
+	for _, change := range diff.Removed {
+		pterm.FgRed.Printf("- [profile %s]\n", change.Name)
+	}
+	for _, change := range diff.Changed {
+		pterm.FgYellow.Printf("~ [profile %s]\n", change.Name)
+		for _, key := range change.Keys {
+			switch {
+			case key.Old == "":
+				pterm.FgGreen.Printf("    + %s = %s\n", key.Key, key.New)
+			case key.New == "":
+				pterm.FgRed.Printf("    - %s = %s\n", key.Key, key.Old)
+			default:
+				pterm.FgYellow.Printf("    ~ %s: %s -> %s\n", key.Key, key.Old, key.New)
+			}
+		}
+	}
+
+	fmt.Println()
+	pterm.FgGray.Printf("%d added, %d removed, %d changed\n", len(diff.Added), len(diff.Removed), len(diff.Changed))
+	fmt.Println()
+}
+
+// printAccountErrors shows the accounts whose roles could not be listed
+func printAccountErrors(accounts []aws.AccountError) {
+	tableData := pterm.TableData{
+		{"Account ID", "Account", "Error"},
+	}
+	for _, account := range accounts {
+		tableData = append(tableData, []string{
+			account.AccountID,
+			account.AccountName,
+			pterm.FgRed.Sprint(account.Err),
+		})
+	}
+
+	fmt.Println()
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	fmt.Println()
+	pterm.FgGray.Println("Existing profiles for these accounts were kept")
+	fmt.Println()
+}
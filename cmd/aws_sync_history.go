@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devops-chris/cloudctx/internal/aws"
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var awsSyncHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show past AWS SSO syncs",
+	Long: `Show the results of past syncs, newest first.
+
+Lists when each sync ran, how many accounts and profiles it found,
+which accounts were skipped, and which accounts appeared or disappeared
+since the previous sync.
+
+Examples:
+  cloudctx aws sync history
+  cloudctx aws sync history -n 30
+  cloudctx aws sync history --json`,
+	Args: cobra.NoArgs,
+	RunE: runAWSSyncHistory,
+}
+
+var (
+	awsSyncHistoryLimit int
+	awsSyncHistoryJSON  bool
+)
+
+func init() {
+	awsSyncCmd.AddCommand(awsSyncHistoryCmd)
+	awsSyncHistoryCmd.Flags().IntVarP(&awsSyncHistoryLimit, "limit", "n", 10, "number of syncs to show")
+	awsSyncHistoryCmd.Flags().BoolVar(&awsSyncHistoryJSON, "json", false, "output as JSON")
+}
+
+func runAWSSyncHistory(cmd *cobra.Command, args []string) error {
+	p := newAWSProvider()
+
+	history, err := p.SyncHistory()
+	if err != nil {
+		return err
+	}
+
+	if len(history) == 0 {
+		pterm.Warning.Println("No syncs recorded yet")
+		pterm.FgGray.Println("Run 'cloudctx aws sync' to fetch profiles from SSO")
+		return nil
+	}
+
+	// Newest first, limited
+	start := 0
+	if awsSyncHistoryLimit > 0 && len(history) > awsSyncHistoryLimit {
+		start = len(history) - awsSyncHistoryLimit
+	}
+
+	if awsSyncHistoryJSON {
+		recent := make([]provider.SyncResult, 0, len(history)-start)
+		for i := len(history) - 1; i >= start; i-- {
+			recent = append(recent, history[i])
+		}
+		data, err := json.MarshalIndent(recent, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Println()
+	pterm.DefaultHeader.WithBackgroundStyle(pterm.NewStyle(pterm.BgDarkGray)).
+		WithTextStyle(pterm.NewStyle(pterm.FgLightWhite)).
+		Println("AWS Sync History")
+
+	// Show the instance column only when several SSO instances are synced
+	showInstance := false
+	for _, entry := range history {
+		if entry.Instance != "" {
+			showInstance = true
+		}
+	}
+
+	header := []string{"Time", "Duration", "Accounts", "Profiles", "Skipped", "Account Changes"}
+	if showInstance {
+		header = append([]string{"Instance"}, header...)
+	}
+	tableData := pterm.TableData{header}
+
+	for i := len(history) - 1; i >= start; i-- {
+		entry := history[i]
+
+		// Compare with the previous sync of the same SSO instance
+		changes := ""
+		for j := i - 1; j >= 0; j-- {
+			if history[j].Instance == entry.Instance {
+				added, removed := aws.AccountChanges(&history[j], &entry)
+				changes = formatAccountChanges(added, removed)
+				break
+			}
+		}
+
+		skipped := ""
+		if len(entry.Skipped) > 0 {
+			skipped = pterm.FgYellow.Sprint(len(entry.Skipped))
+		}
+
+		row := []string{
+			entry.Time.Local().Format("2006-01-02 15:04"),
+			entry.Duration.Round(time.Second).String(),
+			fmt.Sprint(len(entry.Accounts)),
+			fmt.Sprint(entry.Profiles),
+			skipped,
+			changes,
+		}
+		if showInstance {
+			instance := entry.Instance
+			if instance == "" {
+				instance = "default"
+			}
+			row = append([]string{instance}, row...)
+		}
+		tableData = append(tableData, row)
+	}
+
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	fmt.Println()
+
+	return nil
+}
+
+// formatAccountChanges renders accounts that appeared (+) and disappeared (-)
+func formatAccountChanges(added, removed []provider.SyncedAccount) string {
+	var parts []string
+	for _, account := range added {
+		parts = append(parts, pterm.FgGreen.Sprintf("+%s", accountLabel(account)))
+	}
+	for _, account := range removed {
+		parts = append(parts, pterm.FgRed.Sprintf("-%s", accountLabel(account)))
+	}
+	return strings.Join(parts, " ")
+}
+
+func accountLabel(account provider.SyncedAccount) string {
+	if account.Name == "" {
+		return account.ID
+	}
+	return fmt.Sprintf("%s (%s)", account.Name, account.ID)
+}
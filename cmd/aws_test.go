@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/devops-chris/cloudctx/internal/provider"
+)
+
+func TestMatchProfiles(t *testing.T) {
+	contexts := []provider.Context{
+		{Name: "prod:admin", AccountID: "111111111111", Role: "Admin"},
+		{Name: "prod:readonly", AccountID: "111111111111", Role: "ReadOnly"},
+		{Name: "dev:admin", AccountID: "222222222222", Role: "Admin"},
+	}
+
+	tests := map[string][]string{
+		"prod:admin":   {"prod:admin"},
+		"admin":        {"prod:admin", "dev:admin"},
+		"111111111111": {"prod:admin", "prod:readonly"},
+		"arn:aws:sts::111111111111:assumed-role/AWSReservedSSO_ReadOnly_0123abcd/jane": {"prod:readonly"},
+		"arn:aws:iam::111111111111:role/Deploy":                                        {"prod:admin", "prod:readonly"},
+		"333333333333":                                                                 nil,
+	}
+	for name, want := range tests {
+		got := matchProfiles(contexts, name)
+		if len(got) != len(want) {
+			t.Errorf("%s: got %d profile(s), want %v", name, len(got), want)
+			continue
+		}
+		for i := range got {
+			if got[i].Name != want[i] {
+				t.Errorf("%s: got %s, want %s", name, got[i].Name, want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/devops-chris/cloudctx/internal/aws"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var awsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check which AWS profiles actually work",
+	Long: `Verify access for every AWS profile (or those matching --filter).
+
+Each profile's credentials are resolved and checked with STS
+GetCallerIdentity, several profiles at a time. The current profile is not
+changed. Each profile is reported as:
+
+  ok              credentials work
+  expired         SSO token or credentials expired ('cloudctx aws login')
+  access-denied   credentials rejected, e.g. a removed permission set
+  misconfigured   anything else (missing keys, unknown sso-session, ...)
+
+The last result per profile is stored and shown in 'cloudctx aws list'.
+The command fails if any profile is not ok.
+
+Examples:
+  cloudctx aws verify
+  cloudctx aws verify --filter 'prod-*'
+  cloudctx aws verify --sso --json`,
+	Args: cobra.NoArgs,
+	RunE: runAWSVerify,
+}
+
+var (
+	awsVerifyFilter   string
+	awsVerifyParallel int
+	awsVerifyJSON     bool
+)
+
+func init() {
+	awsCmd.AddCommand(awsVerifyCmd)
+	awsVerifyCmd.Flags().StringVarP(&awsVerifyFilter, "filter", "f", "", "only profiles matching this pattern")
+	awsVerifyCmd.Flags().IntVarP(&awsVerifyParallel, "parallel", "p", 8, "number of profiles to verify at once")
+	awsVerifyCmd.Flags().BoolVar(&awsVerifyJSON, "json", false, "output as JSON")
+	awsVerifyCmd.Flags().BoolVar(&awsSSOOnly, "sso", false, "only SSO-synced profiles")
+	awsVerifyCmd.Flags().BoolVar(&awsManualOnly, "manual", false, "only manually created profiles")
+	awsVerifyCmd.Flags().BoolVar(&awsFavorites, "favorites", false, "only favorite profiles")
+}
+
+func runAWSVerify(cmd *cobra.Command, args []string) error {
+	p := newAWSProvider()
+
+	contexts, err := p.ListContexts()
+	if err != nil {
+		return err
+	}
+	contexts = filterEachContexts(filterContexts(contexts), awsVerifyFilter)
+
+	if len(contexts) == 0 {
+		pterm.Warning.Println("No AWS profiles match")
+		return nil
+	}
+
+	names := make([]string, len(contexts))
+	for i, ctx := range contexts {
+		names[i] = ctx.Name
+	}
+
+	var spinner *pterm.SpinnerPrinter
+	if !awsVerifyJSON {
+		spinner, _ = pterm.DefaultSpinner.Start(fmt.Sprintf("Verifying %d profile(s)...", len(names)))
+	}
+	results, err := p.Verify(names, awsVerifyParallel)
+	if spinner != nil {
+		_ = spinner.Stop()
+	}
+	if err != nil {
+		// The results are still usable when only saving them failed
+		pterm.Warning.Println(err)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status != aws.VerifyOK {
+			failed++
+		}
+	}
+
+	if awsVerifyJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		printVerifyResults(results)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d profile(s) failed verification", failed, len(results))
+	}
+	if !awsVerifyJSON {
+		pterm.Success.Printf("All %d profile(s) verified\n", len(results))
+	}
+	return nil
+}
+
+func printVerifyResults(results []aws.VerifyResult) {
+	fmt.Println()
+	tableData := pterm.TableData{
+		{"Profile", "Status", "Account ID", "Details"},
+	}
+	for _, result := range results {
+		details := result.ARN
+		if result.Error != "" {
+			details = pterm.FgGray.Sprint(result.Error)
+		}
+		tableData = append(tableData, []string{
+			result.Profile,
+			verifyStatusStyle(result.Status),
+			result.AccountID,
+			details,
+		})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	fmt.Println()
+}
+
+// verifyStatusStyle colors a verification status
+func verifyStatusStyle(status aws.VerifyStatus) string {
+	switch status {
+	case aws.VerifyOK:
+		return pterm.FgGreen.Sprint(status)
+	case aws.VerifyExpired:
+		return pterm.FgYellow.Sprint(status)
+	default:
+		return pterm.FgRed.Sprint(status)
+	}
+}
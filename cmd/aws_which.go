@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/devops-chris/cloudctx/internal/aws"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var awsWhichCmd = &cobra.Command{
+	Use:   "which <arn|account-id>",
+	Short: "Find the profiles for an account ID or ARN",
+	Long: `List the AWS profiles that grant access to an account.
+
+Takes a 12-digit account ID or any ARN, e.g. one copied from a log line or
+an AccessDenied message. When the ARN names a role (an IAM role, an assumed
+role or an IAM Identity Center permission set), profiles with that role are
+highlighted.
+
+Examples:
+  cloudctx aws which 123456789012
+  cloudctx aws which arn:aws:sts::123456789012:assumed-role/AWSReservedSSO_Admin_0123abcd/jane`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAWSWhich,
+}
+
+func init() {
+	awsCmd.AddCommand(awsWhichCmd)
+}
+
+func runAWSWhich(cmd *cobra.Command, args []string) error {
+	query, ok := aws.ParseAccountQuery(args[0])
+	if !ok {
+		return fmt.Errorf("'%s' is not an account ID or ARN", args[0])
+	}
+
+	contexts, err := newAWSProvider().ListContexts()
+	if err != nil {
+		return err
+	}
+
+	matches, _ := matchAccount(contexts, query)
+	if len(matches) == 0 {
+		pterm.Warning.Printf("No profile grants access to account %s\n", query.AccountID)
+		pterm.FgGray.Println("Run 'cloudctx aws sync' to fetch profiles from SSO")
+		return nil
+	}
+
+	account := query.AccountID
+	for _, ctx := range matches {
+		if ctx.AccountName != "" {
+			account = fmt.Sprintf("%s (%s)", ctx.AccountName, query.AccountID)
+			break
+		}
+	}
+
+	fmt.Println()
+	pterm.Info.Printf("Profiles for %s\n", account)
+	fmt.Println()
+
+	tableData := pterm.TableData{
+		{"Profile", "Role", "Region", "Type"},
+	}
+	for _, ctx := range matches {
+		role := ctx.Role
+		if query.MatchesRole(ctx) {
+			role = pterm.FgGreen.Sprint(ctx.Role)
+		}
+		tableData = append(tableData, []string{ctx.Name, role, ctx.Region, ctx.Type})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	fmt.Println()
+	pterm.FgGray.Println("Switch with: cloudctx aws <profile>")
+	return nil
+}
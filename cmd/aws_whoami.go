@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/devops-chris/cloudctx/internal/aws"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 )
@@ -32,7 +31,7 @@ func init() {
 }
 
 func runAWSWhoami(cmd *cobra.Command, args []string) error {
-	p := aws.NewProvider(cfg.AWS.SSOStartURL, cfg.AWS.SSORegion, cfg.AWS.DefaultRegion)
+	p := newAWSProvider()
 
 	identity, err := p.WhoAmI()
 	if err != nil {
@@ -66,6 +65,9 @@ func runAWSWhoami(cmd *cobra.Command, args []string) error {
 		tableData = append(tableData, []string{"Profile", pterm.FgCyan.Sprint(currentProfile)})
 	}
 	tableData = append(tableData, []string{"Account", identity.AccountID})
+	if identity.AccountName != "" {
+		tableData = append(tableData, []string{"Account Name", identity.AccountName})
+	}
 	tableData = append(tableData, []string{"User ID", identity.UserID})
 	tableData = append(tableData, []string{"ARN", identity.ARN})
 	tableData = append(tableData, []string{"Region", identity.Region})
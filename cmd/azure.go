@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"github.com/devops-chris/cloudctx/internal/azure"
+	"github.com/devops-chris/cloudctx/internal/favorites"
+	"github.com/devops-chris/cloudctx/internal/history"
 	"github.com/devops-chris/cloudctx/internal/provider"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
@@ -23,15 +25,21 @@ Examples:
   cloudctx azure                    # Interactive picker
   cloudctx azure my-subscription    # Set specific subscription
   cloudctx azure -c                 # Show current subscription
-  cloudctx azure -l                 # List all subscriptions`,
-	Aliases: []string{"az"},
-	Args:    cobra.MaximumNArgs(1),
-	RunE:    runAzure,
+  cloudctx azure -l                 # List all subscriptions
+  cloudctx azure -                  # Switch back to the previous subscription
+  cloudctx azure --favorites        # Pick from favorite subscriptions
+  cloudctx azure prod -g            # Set the az default with shell integration loaded`,
+	Aliases:           []string{"az"},
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeAzureSubscriptions,
+	RunE:              runAzure,
 }
 
 var (
 	azureShowCurrent bool
 	azureShowList    bool
+	azureFavorites   bool
+	azureGlobal      bool
 )
 
 func init() {
@@ -39,6 +47,8 @@ func init() {
 
 	azureCmd.Flags().BoolVarP(&azureShowCurrent, "current", "c", false, "show current subscription")
 	azureCmd.Flags().BoolVarP(&azureShowList, "list", "l", false, "list all subscriptions")
+	azureCmd.Flags().BoolVar(&azureFavorites, "favorites", false, "show only favorite subscriptions")
+	azureCmd.Flags().BoolVarP(&azureGlobal, "global", "g", false, "set the Azure CLI default even with shell integration loaded")
 }
 
 func runAzure(cmd *cobra.Command, args []string) error {
@@ -54,6 +64,11 @@ func runAzure(cmd *cobra.Command, args []string) error {
 		return listAzure(p)
 	}
 
+	// Switch back to the previous subscription
+	if len(args) == 1 && args[0] == "-" {
+		return previousAzure(p)
+	}
+
 	// Set specific subscription
 	if len(args) == 1 {
 		return setAzure(p, args[0])
@@ -87,9 +102,10 @@ func listAzure(p *azure.Provider) error {
 		return err
 	}
 
+	contexts = filterAzureContexts(contexts)
+
 	if len(contexts) == 0 {
-		pterm.Warning.Println("No Azure subscriptions found")
-		pterm.FgGray.Println("Run 'cloudctx azure login' to authenticate")
+		warnNoAzureSubscriptions()
 		return nil
 	}
 
@@ -102,6 +118,7 @@ func listAzure(p *azure.Provider) error {
 		{"", "Subscription", "Subscription ID"},
 	}
 
+	favs := favorites.Set("azure")
 	for _, ctx := range contexts {
 		marker := " "
 		name := ctx.Name
@@ -109,6 +126,7 @@ func listAzure(p *azure.Provider) error {
 			marker = "*"
 			name = pterm.FgGreen.Sprint(ctx.Name)
 		}
+		marker += favoriteMarker(favs[ctx.Name])
 		tableData = append(tableData, []string{
 			marker,
 			name,
@@ -117,11 +135,41 @@ func listAzure(p *azure.Provider) error {
 	}
 
 	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
-	fmt.Printf("\nTotal: %d subscription(s)\n\n", len(contexts))
+	filterNote := ""
+	if azureFavorites {
+		filterNote = " (favorites)"
+	}
+	fmt.Printf("\nTotal: %d subscription(s)%s\n\n", len(contexts), filterNote)
 
 	return nil
 }
 
+// filterAzureContexts applies the --favorites flag
+func filterAzureContexts(contexts []provider.Context) []provider.Context {
+	if !azureFavorites {
+		return contexts // No filter
+	}
+
+	favs := favorites.Set("azure")
+	var filtered []provider.Context
+	for _, ctx := range contexts {
+		if favs[ctx.Name] {
+			filtered = append(filtered, ctx)
+		}
+	}
+	return filtered
+}
+
+// warnNoAzureSubscriptions explains an empty subscription list under the current filters
+func warnNoAzureSubscriptions() {
+	pterm.Warning.Println("No Azure subscriptions found")
+	if azureFavorites {
+		pterm.FgGray.Println("Add favorites with 'cloudctx fav add --cloud azure <subscription>'")
+	} else {
+		pterm.FgGray.Println("Run 'cloudctx azure login' to authenticate")
+	}
+}
+
 func setAzure(p *azure.Provider, name string) error {
 	contexts, err := p.ListContexts()
 	if err != nil {
@@ -155,6 +203,20 @@ func setAzure(p *azure.Provider, name string) error {
 	return selectAzureSubscription(p, matches[0].Name)
 }
 
+// previousAzure switches to the subscription that was active before the last switch
+func previousAzure(p *azure.Provider) error {
+	previous, err := history.Previous("azure")
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		pterm.Warning.Println("No previous Azure subscription")
+		pterm.FgGray.Println("Switch subscriptions with 'cloudctx azure <subscription>' first")
+		return nil
+	}
+	return selectAzureSubscription(p, previous.Name)
+}
+
 func interactiveAzure(p *azure.Provider) error {
 	contexts, err := p.ListContexts()
 	if err != nil {
@@ -163,9 +225,10 @@ func interactiveAzure(p *azure.Provider) error {
 		return err
 	}
 
+	contexts = filterAzureContexts(contexts)
+
 	if len(contexts) == 0 {
-		pterm.Warning.Println("No Azure subscriptions found")
-		pterm.FgGray.Println("Run 'cloudctx azure login' to authenticate")
+		warnNoAzureSubscriptions()
 		return nil
 	}
 
@@ -180,14 +243,20 @@ func pickAzureFromMatches(p *azure.Provider, contexts []provider.Context) error
 		currentName = current.Name
 	}
 
+	// Favorites first
+	favs := favorites.Set("azure")
+	contexts = favorites.Pin(contexts, favs)
+
 	// Build options
 	options := make([]string, len(contexts))
+	subscriptionNames := make(map[string]string, len(contexts))
 	for i, ctx := range contexts {
+		marker := " "
 		if ctx.Name == currentName {
-			options[i] = fmt.Sprintf("* %s", ctx.Name)
-		} else {
-			options[i] = fmt.Sprintf("  %s", ctx.Name)
+			marker = "*"
 		}
+		options[i] = fmt.Sprintf("%s%s %s", marker, favoriteMarker(favs[ctx.Name]), ctx.Name)
+		subscriptionNames[options[i]] = ctx.Name
 	}
 
 	fmt.Println()
@@ -205,18 +274,42 @@ func pickAzureFromMatches(p *azure.Provider, contexts []provider.Context) error
 		return nil // User cancelled
 	}
 
-	// Extract subscription name (remove marker)
-	subName := strings.TrimPrefix(selected, "* ")
-	subName = strings.TrimPrefix(subName, "  ")
-
-	return selectAzureSubscription(p, subName)
+	return selectAzureSubscription(p, subscriptionNames[selected])
 }
 
+// azureSubscriptionEnv are the variables SDKs and Terraform read the subscription from
+var azureSubscriptionEnv = []string{"AZURE_SUBSCRIPTION_ID", "ARM_SUBSCRIPTION_ID"}
+
 func selectAzureSubscription(p *azure.Provider, name string) error {
+	previous := currentContextName(p)
+
+	// With shell integration, only the calling shell switches
+	if shellMode() && !azureGlobal {
+		subscription, err := p.FindSubscription(name)
+		if err != nil {
+			pterm.Error.Printf("Failed to set subscription: %v\n", err)
+			return err
+		}
+		var vars []envVar
+		for _, env := range azureSubscriptionEnv {
+			vars = append(vars, envVar{env, subscription.AccountID})
+		}
+		if err := exportToShell(nil, vars); err != nil {
+			pterm.Error.Printf("Failed to set subscription: %v\n", err)
+			return err
+		}
+		_ = history.Record("azure", previous, name)
+		fmt.Println()
+		pterm.Success.Printf("Switched to %s in this shell\n", pterm.FgCyan.Sprint(name))
+		pterm.FgGray.Println("az commands still use the global default; pass --subscription \"$AZURE_SUBSCRIPTION_ID\"")
+		return nil
+	}
+
 	if err := p.SetContext(name); err != nil {
 		pterm.Error.Printf("Failed to set subscription: %v\n", err)
 		return err
 	}
+	_ = history.Record("azure", previous, name)
 
 	fmt.Println()
 	pterm.Success.Printf("Switched to %s\n", pterm.FgCyan.Sprint(name))
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/devops-chris/cloudctx/internal/azure"
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"github.com/spf13/cobra"
+)
+
+var azureEachCmd = &cobra.Command{
+	Use:   "each [--filter pattern] -- <command> [args...]",
+	Short: "Run a command in every matching Azure subscription",
+	Long: `Run a command once per Azure subscription, with AZURE_SUBSCRIPTION_ID
+(and ARM_SUBSCRIPTION_ID, for Terraform) set for each run.
+
+The current subscription is not changed. Output lines are prefixed with the
+subscription name (or grouped with --group), and a summary of exit codes is
+printed at the end. The command fails if any run fails.
+
+Note that the az CLI itself ignores AZURE_SUBSCRIPTION_ID; pass
+--subscription "$AZURE_SUBSCRIPTION_ID" to az commands.
+
+Examples:
+  cloudctx azure each --filter 'prod*' -- terraform plan
+  cloudctx azure each -- sh -c 'az group list --subscription "$AZURE_SUBSCRIPTION_ID" -o table'`,
+	RunE: runAzureEach,
+}
+
+var azureEachOptions eachOptions
+
+func init() {
+	azureCmd.AddCommand(azureEachCmd)
+	azureEachCmd.Flags().StringVarP(&azureEachOptions.filter, "filter", "f", "", "only subscriptions matching this pattern")
+	azureEachCmd.Flags().IntVarP(&azureEachOptions.parallel, "parallel", "p", 4, "number of commands to run at once")
+	azureEachCmd.Flags().BoolVarP(&azureEachOptions.group, "group", "g", false, "print each subscription's output as one block when it finishes")
+	azureEachCmd.Flags().BoolVar(&azureFavorites, "favorites", false, "only favorite subscriptions")
+}
+
+func runAzureEach(cmd *cobra.Command, args []string) error {
+	command, err := eachCommand(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	contexts, err := azure.NewProvider(cfg.Azure.DefaultLocation).ListContexts()
+	if err != nil {
+		return err
+	}
+
+	contexts = filterEachContexts(filterAzureContexts(contexts), azureEachOptions.filter)
+
+	return runEach(contexts, azureEachOptions, command, func(ctx provider.Context) []string {
+		return eachEnv(azureSubscriptionEnv,
+			"AZURE_SUBSCRIPTION_ID="+ctx.AccountID,
+			"ARM_SUBSCRIPTION_ID="+ctx.AccountID)
+	})
+}
@@ -16,5 +16,6 @@ var azureListCmd = &cobra.Command{
 
 func init() {
 	azureCmd.AddCommand(azureListCmd)
+	azureListCmd.Flags().BoolVar(&azureFavorites, "favorites", false, "show only favorite subscriptions")
 }
 
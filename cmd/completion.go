@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/devops-chris/cloudctx/internal/azure"
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate the autocompletion script for the specified shell",
+	Long: `Generate the autocompletion script for cloudctx and its ctx alias.
+
+Profile and subscription names complete too. AWS profiles are read from a
+cache of ~/.aws/config that is rebuilt when the file changes; Azure
+subscriptions are cached for a few minutes so TAB doesn't run 'az' each time.
+
+Examples:
+  source <(cloudctx completion bash)                 # ~/.bashrc
+  source <(cloudctx completion zsh)                  # ~/.zshrc
+  cloudctx completion fish | source                  # ~/.config/fish/config.fish
+  cloudctx completion powershell | Out-String | Invoke-Expression`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	RunE:      runCompletion,
+}
+
+var completionNoDescriptions bool
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+	completionCmd.Flags().BoolVar(&completionNoDescriptions, "no-descriptions", false, "disable completion descriptions")
+}
+
+// runCompletion generates cobra's completion script and registers it for the
+// ctx alias as well
+func runCompletion(cmd *cobra.Command, args []string) error {
+	name := rootCmd.Name()
+	descriptions := !completionNoDescriptions
+
+	var buf bytes.Buffer
+	switch args[0] {
+	case "bash":
+		if err := rootCmd.GenBashCompletionV2(&buf, descriptions); err != nil {
+			return err
+		}
+		script := strings.ReplaceAll(buf.String(),
+			fmt.Sprintf("-F __start_%s %s", name, name),
+			fmt.Sprintf("-F __start_%s %s ctx", name, name))
+		_, err := os.Stdout.WriteString(script)
+		return err
+	case "zsh":
+		var err error
+		if descriptions {
+			err = rootCmd.GenZshCompletion(&buf)
+		} else {
+			err = rootCmd.GenZshCompletionNoDesc(&buf)
+		}
+		if err != nil {
+			return err
+		}
+		script := strings.Replace(buf.String(),
+			fmt.Sprintf("#compdef %s\ncompdef _%s %s\n", name, name, name),
+			fmt.Sprintf("#compdef %s ctx\ncompdef _%s %s ctx\n", name, name, name), 1)
+		_, err = os.Stdout.WriteString(script)
+		return err
+	case "fish":
+		if err := rootCmd.GenFishCompletion(&buf, descriptions); err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "\ncomplete -c ctx -w %s\n", name)
+	case "powershell":
+		var err error
+		if descriptions {
+			err = rootCmd.GenPowerShellCompletionWithDesc(&buf)
+		} else {
+			err = rootCmd.GenPowerShellCompletion(&buf)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "\nRegister-ArgumentCompleter -CommandName 'ctx' -ScriptBlock ${__%sCompleterBlock}\n", name)
+	default:
+		return fmt.Errorf("unsupported shell '%s' (use bash, zsh, fish or powershell)", args[0])
+	}
+
+	_, err := buf.WriteTo(os.Stdout)
+	return err
+}
+
+// completeAWSProfiles completes the profile argument of AWS commands
+func completeAWSProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	contexts, err := newAWSProvider().CachedContexts()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return contextCompletions(filterContexts(contexts), toComplete, func(ctx provider.Context) string {
+		if ctx.AccountName == "" {
+			return ctx.Role
+		}
+		return strings.TrimSpace(ctx.AccountName + " " + ctx.Role)
+	}), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeAzureSubscriptions completes the subscription argument of Azure commands
+func completeAzureSubscriptions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	contexts, err := azure.NewProvider(cfg.Azure.DefaultLocation).CachedContexts()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return contextCompletions(contexts, toComplete, func(ctx provider.Context) string {
+		return ctx.AccountID
+	}), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRootContexts completes 'ctx <name>' for the default cloud
+func completeRootContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch cfg.DefaultCloud {
+	case "azure", "az":
+		return completeAzureSubscriptions(cmd, args, toComplete)
+	default:
+		return completeAWSProfiles(cmd, args, toComplete)
+	}
+}
+
+// contextCompletions returns the names starting with toComplete, each with
+// a description
+func contextCompletions(contexts []provider.Context, toComplete string, describe func(provider.Context) string) []string {
+	var completions []string
+	for _, ctx := range contexts {
+		if !strings.HasPrefix(ctx.Name, toComplete) {
+			continue
+		}
+		if description := describe(ctx); description != "" {
+			completions = append(completions, ctx.Name+"\t"+description)
+		} else {
+			completions = append(completions, ctx.Name)
+		}
+	}
+	return completions
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/devops-chris/cloudctx/internal/config"
+)
+
+func TestCompleteAWSProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	origCfg := cfg
+	cfg = config.DefaultConfig()
+	t.Cleanup(func() { cfg = origCfg })
+
+	awsConfig := `[profile prod:admin]
+sso_session = cloudctx
+sso_account_id = 111111111111
+sso_account_name = Prod
+sso_role_name = Admin
+
+[profile prod:readonly]
+sso_session = cloudctx
+sso_account_id = 111111111111
+sso_role_name = ReadOnly
+
+[profile dev]
+region = us-east-1
+`
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := completeAWSProfiles(awsCmd, nil, "prod:")
+	want := []string{"prod:admin\tProd Admin", "prod:readonly\tReadOnly"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Only the first argument is a profile
+	if got, _ := completeAWSProfiles(awsCmd, []string{"dev"}, ""); len(got) != 0 {
+		t.Errorf("got %q for a second argument", got)
+	}
+}
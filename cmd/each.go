@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+// eachOptions are the flags shared by 'aws each' and 'azure each'
+type eachOptions struct {
+	filter   string
+	parallel int
+	group    bool
+}
+
+// eachResult is the outcome of running the command for one context
+type eachResult struct {
+	context  provider.Context
+	exitCode int
+	err      error // set when the command could not be run at all
+	duration time.Duration
+}
+
+// prefixColors cycles through colors so interleaved output stays readable
+var prefixColors = []pterm.Color{pterm.FgCyan, pterm.FgMagenta, pterm.FgYellow, pterm.FgBlue, pterm.FgGreen, pterm.FgLightRed}
+
+// filterEachContexts keeps the contexts whose name, account name or account
+// ID matches pattern: a glob ("prod-*") when it contains * or ?, otherwise a
+// substring, case-insensitively
+func filterEachContexts(contexts []provider.Context, pattern string) []provider.Context {
+	if pattern == "" {
+		return contexts
+	}
+
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern))
+	if strings.ContainsAny(pattern, "*?") {
+		re = globRegexp(pattern)
+	}
+
+	var matched []provider.Context
+	for _, ctx := range contexts {
+		if re.MatchString(ctx.Name) || re.MatchString(ctx.AccountName) || re.MatchString(ctx.AccountID) {
+			matched = append(matched, ctx)
+		}
+	}
+	return matched
+}
+
+// globRegexp compiles a case-insensitive glob, where * matches any text
+// (including "/") and ? a single character
+func globRegexp(pattern string) *regexp.Regexp {
+	expr := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern))
+	return regexp.MustCompile("(?i)^" + expr + "$")
+}
+
+// eachCommand returns the command given after "--"
+func eachCommand(cmd *cobra.Command, args []string) ([]string, error) {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 || len(args) == dash {
+		return nil, fmt.Errorf("no command given; put it after '--', e.g. -- aws s3 ls")
+	}
+	if dash > 0 {
+		return nil, fmt.Errorf("unexpected arguments before '--': %s (use --filter)", strings.Join(args[:dash], " "))
+	}
+	return args, nil
+}
+
+// runEach runs a command once per context, at most opts.parallel at a time,
+// with the environment from envFor. Output is prefixed with the context name
+// (or grouped per context with opts.group), followed by a summary of exit
+// codes. It returns an error if any command failed.
+func runEach(contexts []provider.Context, opts eachOptions, args []string, envFor func(provider.Context) []string) error {
+	if len(contexts) == 0 {
+		pterm.Warning.Println("No contexts match")
+		return nil
+	}
+
+	parallel := opts.parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	width := 0
+	for _, ctx := range contexts {
+		if len(ctx.Name) > width {
+			width = len(ctx.Name)
+		}
+	}
+
+	pterm.Info.Printf("Running '%s' in %d context(s)\n", strings.Join(args, " "), len(contexts))
+	fmt.Println()
+
+	var outputMu sync.Mutex
+	results := make([]eachResult, len(contexts))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, ctx := range contexts {
+		wg.Add(1)
+		go func(i int, ctx provider.Context) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			color := prefixColors[i%len(prefixColors)]
+			prefix := color.Sprintf("%-*s", width, ctx.Name) + " │ "
+
+			var stdout, stderr io.Writer
+			var grouped bytes.Buffer
+			var flushers []*prefixWriter
+			if opts.group {
+				stdout, stderr = &grouped, &grouped
+			} else {
+				out := &prefixWriter{mu: &outputMu, out: os.Stdout, prefix: prefix}
+				errOut := &prefixWriter{mu: &outputMu, out: os.Stderr, prefix: prefix}
+				stdout, stderr = out, errOut
+				flushers = append(flushers, out, errOut)
+			}
+
+			results[i] = runEachCommand(ctx, args, envFor(ctx), stdout, stderr)
+
+			for _, f := range flushers {
+				f.Flush()
+			}
+			if opts.group {
+				outputMu.Lock()
+				fmt.Println(color.Sprint("── " + ctx.Name))
+				_, _ = os.Stdout.Write(grouped.Bytes())
+				fmt.Println()
+				outputMu.Unlock()
+			}
+		}(i, ctx)
+	}
+	wg.Wait()
+
+	return printEachSummary(results)
+}
+
+// runEachCommand runs the command for one context
+func runEachCommand(ctx provider.Context, args, env []string, stdout, stderr io.Writer) eachResult {
+	start := time.Now()
+	command := exec.Command(args[0], args[1:]...)
+	command.Env = env
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	result := eachResult{context: ctx}
+	err := command.Run()
+	result.duration = time.Since(start)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.exitCode = exitErr.ExitCode()
+	default:
+		result.exitCode = -1
+		result.err = err
+	}
+	return result
+}
+
+// printEachSummary prints each context's exit code and returns an error if
+// any command failed
+func printEachSummary(results []eachResult) error {
+	fmt.Println()
+	tableData := pterm.TableData{{"Context", "Exit", "Duration"}}
+	failed := 0
+	for _, result := range results {
+		status := pterm.FgGreen.Sprint("0")
+		switch {
+		case result.err != nil:
+			status = pterm.FgRed.Sprintf("error: %v", result.err)
+			failed++
+		case result.exitCode != 0:
+			status = pterm.FgRed.Sprint(result.exitCode)
+			failed++
+		}
+		tableData = append(tableData, []string{
+			result.context.Name,
+			status,
+			result.duration.Round(10 * time.Millisecond).String(),
+		})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	fmt.Println()
+
+	if failed > 0 {
+		return fmt.Errorf("command failed in %d of %d context(s)", failed, len(results))
+	}
+	pterm.Success.Printf("Command succeeded in all %d context(s)\n", len(results))
+	return nil
+}
+
+// eachEnv returns the current environment without the given variables, plus extra
+func eachEnv(remove []string, extra ...string) []string {
+	drop := make(map[string]bool)
+	for _, name := range remove {
+		drop[name] = true
+	}
+
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !drop[name] {
+			env = append(env, kv)
+		}
+	}
+	return append(env, extra...)
+}
+
+// prefixWriter writes complete lines with a prefix, holding back a partial
+// last line until more output (or Flush) completes it
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a remaining partial line
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = io.WriteString(w.out, w.prefix)
+	_, _ = w.out.Write(line)
+}
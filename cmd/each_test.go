@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/devops-chris/cloudctx/internal/provider"
+)
+
+func TestFilterEachContexts(t *testing.T) {
+	contexts := []provider.Context{
+		{Name: "prod-api:admin", AccountName: "Prod API", AccountID: "111111111111"},
+		{Name: "prod-web:admin", AccountName: "Prod Web", AccountID: "222222222222"},
+		{Name: "dev:admin", AccountName: "Development", AccountID: "333333333333"},
+	}
+
+	tests := map[string]int{
+		"":             3,
+		"prod-*":       2,
+		"PROD":         2,
+		"*:admin":      3,
+		"development":  1,
+		"222222222222": 1,
+		"staging":      0,
+	}
+	for pattern, want := range tests {
+		if got := filterEachContexts(contexts, pattern); len(got) != want {
+			t.Errorf("%q matched %d context(s), want %d", pattern, len(got), want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/devops-chris/cloudctx/internal/azure"
+	"github.com/devops-chris/cloudctx/internal/favorites"
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var favCmd = &cobra.Command{
+	Use:   "fav",
+	Short: "Manage favorite profiles and subscriptions",
+	Long: `Manage favorite AWS profiles and Azure subscriptions.
+
+Favorites are pinned at the top of the interactive pickers and marked with ★
+in pickers and lists. --favorites on 'cloudctx aws' and 'cloudctx azure'
+shows only favorites. They are stored in ~/.config/cloudctx/favorites.json,
+so syncing profiles doesn't remove them.
+
+Commands use default_cloud unless --cloud is given.
+
+Examples:
+  cloudctx fav add prod:admin
+  cloudctx fav add --cloud azure "Production"
+  cloudctx fav rm prod:admin
+  cloudctx fav ls`,
+}
+
+var favAddCmd = &cobra.Command{
+	Use:               "add <name>",
+	Short:             "Add a profile or subscription to the favorites",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeFavCandidates,
+	RunE:              runFavAdd,
+}
+
+var favRmCmd = &cobra.Command{
+	Use:               "rm <name>",
+	Aliases:           []string{"remove"},
+	Short:             "Remove a profile or subscription from the favorites",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeFavorites,
+	RunE:              runFavRm,
+}
+
+var favLsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List favorites (of all clouds unless --cloud is given)",
+	Args:    cobra.NoArgs,
+	RunE:    runFavLs,
+}
+
+var favCloud string
+
+func init() {
+	rootCmd.AddCommand(favCmd)
+	favCmd.AddCommand(favAddCmd)
+	favCmd.AddCommand(favRmCmd)
+	favCmd.AddCommand(favLsCmd)
+	favCmd.PersistentFlags().StringVar(&favCloud, "cloud", "", "aws or azure (default: default_cloud)")
+}
+
+// favoriteClouds are the clouds favorites can be kept for, in listing order
+var favoriteClouds = []string{"aws", "azure"}
+
+// favoriteMarker returns the marker shown next to favorites in lists and pickers
+func favoriteMarker(favorite bool) string {
+	if favorite {
+		return "★"
+	}
+	return " "
+}
+
+// favTargetCloud returns the cloud 'fav add' and 'fav rm' work on
+func favTargetCloud() (string, error) {
+	cloud := favCloud
+	if cloud == "" {
+		cloud = cfg.DefaultCloud
+	}
+	switch cloud {
+	case "", "aws":
+		return "aws", nil
+	case "azure", "az":
+		return "azure", nil
+	default:
+		return "", fmt.Errorf("unsupported cloud: %s (supported: aws, azure)", cloud)
+	}
+}
+
+// favCandidates returns the contexts of a cloud that can be added as favorites
+func favCandidates(cloud string) ([]provider.Context, error) {
+	if cloud == "azure" {
+		return azure.NewProvider(cfg.Azure.DefaultLocation).CachedContexts()
+	}
+	return newAWSProvider().CachedContexts()
+}
+
+func runFavAdd(cmd *cobra.Command, args []string) error {
+	cloud, err := favTargetCloud()
+	if err != nil {
+		return err
+	}
+
+	contexts, err := favCandidates(cloud)
+	if err != nil {
+		return err
+	}
+
+	// Store the name even when an Azure subscription is given by ID
+	name := ""
+	for _, ctx := range contexts {
+		if ctx.Name == args[0] || (cloud == "azure" && ctx.AccountID == args[0]) {
+			name = ctx.Name
+			break
+		}
+	}
+	if name == "" {
+		if cloud == "azure" {
+			return fmt.Errorf("no Azure subscription named '%s'", args[0])
+		}
+		return fmt.Errorf("no AWS profile named '%s'", args[0])
+	}
+
+	added, err := favorites.Add(cloud, name)
+	if err != nil {
+		return err
+	}
+	if !added {
+		pterm.Info.Printf("%s is already a favorite\n", pterm.FgCyan.Sprint(name))
+		return nil
+	}
+	pterm.Success.Printf("Added %s to %s favorites\n", pterm.FgCyan.Sprint(name), cloud)
+	return nil
+}
+
+func runFavRm(cmd *cobra.Command, args []string) error {
+	cloud, err := favTargetCloud()
+	if err != nil {
+		return err
+	}
+
+	removed, err := favorites.Remove(cloud, args[0])
+	if err != nil {
+		return err
+	}
+	if !removed {
+		pterm.Warning.Printf("%s is not a %s favorite\n", args[0], cloud)
+		return nil
+	}
+	pterm.Success.Printf("Removed %s from %s favorites\n", pterm.FgCyan.Sprint(args[0]), cloud)
+	return nil
+}
+
+func runFavLs(cmd *cobra.Command, args []string) error {
+	clouds := favoriteClouds
+	if favCloud != "" {
+		cloud, err := favTargetCloud()
+		if err != nil {
+			return err
+		}
+		clouds = []string{cloud}
+	}
+
+	favs, err := favorites.Load()
+	if err != nil {
+		return err
+	}
+
+	tableData := pterm.TableData{
+		{"Cloud", "Name"},
+	}
+	for _, cloud := range clouds {
+		for _, name := range favs[cloud] {
+			tableData = append(tableData, []string{cloud, name})
+		}
+	}
+
+	if len(tableData) == 1 {
+		pterm.Warning.Println("No favorites yet")
+		pterm.FgGray.Println("Add one with: cloudctx fav add <name>")
+		return nil
+	}
+
+	fmt.Println()
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	fmt.Printf("\nTotal: %d favorite(s)\n\n", len(tableData)-1)
+	return nil
+}
+
+// completeFavCandidates completes 'fav add' with the cloud's contexts
+func completeFavCandidates(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cloud, err := favTargetCloud()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if cloud == "azure" {
+		return completeAzureSubscriptions(cmd, args, toComplete)
+	}
+	return completeAWSProfiles(cmd, args, toComplete)
+}
+
+// completeFavorites completes 'fav rm' with the cloud's favorites
+func completeFavorites(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cloud, err := favTargetCloud()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	favs, err := favorites.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var contexts []provider.Context
+	for _, name := range favs[cloud] {
+		contexts = append(contexts, provider.Context{Name: name})
+	}
+	return contextCompletions(contexts, toComplete, func(provider.Context) string {
+		return ""
+	}), cobra.ShellCompDirectiveNoFileComp
+}
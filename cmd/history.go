@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/devops-chris/cloudctx/internal/azure"
+	"github.com/devops-chris/cloudctx/internal/history"
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [n]",
+	Short: "Show recent context switches and jump back to one",
+	Long: `Show recently used AWS profiles and Azure subscriptions, newest first.
+
+Without arguments, opens a picker to switch back to one of them.
+With a number, switches to that entry of 'cloudctx history -l'.
+
+Use 'ctx aws -' or 'ctx azure -' (or 'ctx -' for the default cloud) to
+switch back to the previous context directly.
+
+Examples:
+  cloudctx history           # Pick a recent context
+  cloudctx history -l        # List recent contexts
+  cloudctx history 2         # Switch to the second entry
+  cloudctx history -l --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runHistory,
+}
+
+var (
+	historyShowList bool
+	historyLimit    int
+	historyJSON     bool
+)
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().BoolVarP(&historyShowList, "list", "l", false, "list recent contexts")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "number of contexts to show")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "output as JSON (with --list)")
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	recent, err := history.Recent()
+	if err != nil {
+		return err
+	}
+	if historyLimit > 0 && len(recent) > historyLimit {
+		recent = recent[:historyLimit]
+	}
+
+	if len(recent) == 0 {
+		pterm.Warning.Println("No context switches recorded yet")
+		pterm.FgGray.Println("Switch with 'cloudctx aws <profile>' or 'cloudctx azure <subscription>'")
+		return nil
+	}
+
+	// Jump to a numbered entry
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(recent) {
+			return fmt.Errorf("'%s' is not an entry number between 1 and %d", args[0], len(recent))
+		}
+		return switchToHistoryEntry(recent[n-1])
+	}
+
+	if historyShowList {
+		return listHistory(recent)
+	}
+
+	return pickFromHistory(recent)
+}
+
+func listHistory(recent []history.Entry) error {
+	if historyJSON {
+		data, err := json.MarshalIndent(recent, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Println()
+	pterm.DefaultHeader.WithBackgroundStyle(pterm.NewStyle(pterm.BgDarkGray)).
+		WithTextStyle(pterm.NewStyle(pterm.FgLightWhite)).
+		Println("Recent Contexts")
+
+	tableData := pterm.TableData{
+		{"#", "Cloud", "Context", "Last Used"},
+	}
+	for i, entry := range recent {
+		tableData = append(tableData, []string{
+			fmt.Sprint(i + 1),
+			entry.Cloud,
+			entry.Name,
+			entry.Time.Local().Format("2006-01-02 15:04"),
+		})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	fmt.Println()
+	pterm.FgGray.Println("Switch with: cloudctx history <#>")
+	return nil
+}
+
+func pickFromHistory(recent []history.Entry) error {
+	options := make([]string, len(recent))
+	entries := make(map[string]history.Entry, len(recent))
+	for i, entry := range recent {
+		options[i] = fmt.Sprintf("%-6s %-50s %s", entry.Cloud, entry.Name, entry.Time.Local().Format("2006-01-02 15:04"))
+		entries[options[i]] = entry
+	}
+
+	fmt.Println()
+	pterm.Info.Printf("Found %d recent contexts\n", len(recent))
+	pterm.FgGray.Println("Type to filter • Enter to select • Ctrl+C to cancel")
+	fmt.Println()
+
+	selected, err := pterm.DefaultInteractiveSelect.
+		WithOptions(options).
+		WithFilter(true).
+		WithMaxHeight(20).
+		Show()
+
+	if err != nil {
+		return nil // User cancelled
+	}
+
+	return switchToHistoryEntry(entries[selected])
+}
+
+// switchToHistoryEntry switches the entry's cloud to its context
+func switchToHistoryEntry(entry history.Entry) error {
+	switch entry.Cloud {
+	case "aws":
+		return selectProfile(newAWSProvider(), entry.Name)
+	case "azure":
+		return selectAzureSubscription(azure.NewProvider(cfg.Azure.DefaultLocation), entry.Name)
+	default:
+		return fmt.Errorf("unsupported cloud in history: %s", entry.Cloud)
+	}
+}
+
+// currentContextName returns the name of a cloud's current context, so the
+// history can record the context being switched away from, or "" if none
+func currentContextName(p provider.Provider) string {
+	current, err := p.CurrentContext()
+	if err != nil || current == nil {
+		return ""
+	}
+	return current.Name
+}
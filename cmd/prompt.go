@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/devops-chris/cloudctx/internal/azure"
+	"github.com/devops-chris/cloudctx/internal/config"
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"github.com/spf13/cobra"
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print the active contexts for a shell prompt",
+	Long: `Print the active AWS profile and Azure subscription for PS1, starship or tmux.
+
+Only local files are read: profile metadata is cached under
+~/.config/cloudctx/cache until ~/.aws/config, ~/.aws/credentials or the
+Azure CLI's azureProfile.json change, and 'az' is never run. Nothing is
+printed for clouds without an active context.
+
+Each context is rendered with --format (or prompt.format in the config file)
+and colored by its environment tag from prompt.environments. Template fields:
+.Cloud, .Name, .AccountName, .AccountID, .Role, .Region, .Env
+
+--color wraps the colors for where the output ends up:
+  ansi    plain escape codes (starship, fish, PowerShell)
+  bash    escape codes marked non-printing for PS1
+  zsh     escape codes in %{ %} for PROMPT (with setopt PROMPT_SUBST)
+  tmux    #[fg=...] styles for status-left/status-right
+  none    no colors
+
+Examples:
+  PS1='$(cloudctx prompt --color bash) \$ '
+  PROMPT='$(cloudctx prompt --color zsh) %# '
+  cloudctx prompt --cloud aws --format '{{.AccountName}}/{{.Role}}'
+  set -g status-right '#(cloudctx prompt --color tmux)'`,
+	Args: cobra.NoArgs,
+	RunE: runPrompt,
+}
+
+var (
+	promptFormat    string
+	promptCloud     string
+	promptColor     string
+	promptSeparator string
+)
+
+func init() {
+	rootCmd.AddCommand(promptCmd)
+	promptCmd.Flags().StringVar(&promptFormat, "format", "", "Go template for each context (default: prompt.format, \"{{.Cloud}}:{{.Name}}\")")
+	promptCmd.Flags().StringVar(&promptCloud, "cloud", "", "only show this cloud: aws or azure (default: both)")
+	promptCmd.Flags().StringVar(&promptColor, "color", "ansi", "color style: ansi, bash, zsh, tmux or none")
+	promptCmd.Flags().StringVar(&promptSeparator, "separator", " ", "text between contexts")
+}
+
+// promptData is what the prompt format is rendered with
+type promptData struct {
+	provider.Context
+
+	// Env is the context's environment tag, if any
+	Env string
+}
+
+// promptColors are ANSI color codes for environment tag colors
+var promptColors = map[string]string{
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"gray":    "90",
+}
+
+func runPrompt(cmd *cobra.Command, args []string) error {
+	switch promptColor {
+	case "ansi", "bash", "zsh", "tmux", "none":
+	default:
+		return fmt.Errorf("unknown color style '%s' (use ansi, bash, zsh, tmux or none)", promptColor)
+	}
+
+	format := promptFormat
+	if format == "" {
+		format = cfg.Prompt.Format
+	}
+	tmpl, err := template.New("prompt").Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid prompt format: %w", err)
+	}
+
+	environments := cfg.Prompt.Environments
+	if len(environments) == 0 {
+		environments = config.DefaultEnvironments
+	}
+
+	contexts, err := promptContexts(promptCloud)
+	if err != nil {
+		return err
+	}
+
+	var segments []string
+	for _, ctx := range contexts {
+		env := environmentTag(environments, ctx)
+
+		var b strings.Builder
+		if err := tmpl.Execute(&b, promptData{Context: ctx, Env: env.Tag}); err != nil {
+			return fmt.Errorf("invalid prompt format: %w", err)
+		}
+		segments = append(segments, colorizePrompt(escapePrompt(b.String(), promptColor), env.Color, promptColor))
+	}
+
+	if len(segments) > 0 {
+		fmt.Println(strings.Join(segments, promptSeparator))
+	}
+	return nil
+}
+
+// promptContexts returns the active context of each cloud from the cached
+// read path. Errors are ignored so a broken config never breaks the prompt.
+func promptContexts(cloud string) ([]provider.Context, error) {
+	var contexts []provider.Context
+	switch cloud {
+	case "", "aws", "azure", "az":
+	default:
+		return nil, fmt.Errorf("unsupported cloud: %s (supported: aws, azure)", cloud)
+	}
+
+	if cloud == "" || cloud == "aws" {
+		if current, err := newAWSProvider().QuickCurrentContext(); err == nil && current != nil {
+			contexts = append(contexts, *current)
+		}
+	}
+	if cloud == "" || cloud == "azure" || cloud == "az" {
+		p := azure.NewProvider(cfg.Azure.DefaultLocation)
+		if current, err := p.QuickCurrentContext(); err == nil && current != nil {
+			contexts = append(contexts, *current)
+		}
+	}
+	return contexts, nil
+}
+
+// environmentTag returns the first environment whose globs match the
+// context's name, account name or account ID
+func environmentTag(environments []config.EnvironmentTag, ctx provider.Context) config.EnvironmentTag {
+	for _, env := range environments {
+		for _, pattern := range env.Match {
+			re := globRegexp(pattern)
+			if re.MatchString(ctx.Name) || re.MatchString(ctx.AccountName) || re.MatchString(ctx.AccountID) {
+				return env
+			}
+		}
+	}
+	return config.EnvironmentTag{}
+}
+
+// escapePrompt escapes characters the prompt would otherwise interpret
+func escapePrompt(text, style string) string {
+	switch style {
+	case "zsh":
+		return strings.ReplaceAll(text, "%", "%%")
+	case "tmux":
+		return strings.ReplaceAll(text, "#", "##")
+	default:
+		return text
+	}
+}
+
+// colorizePrompt wraps text in a color for the given style
+func colorizePrompt(text, color, style string) string {
+	code, ok := promptColors[strings.ToLower(color)]
+	if !ok {
+		return text
+	}
+
+	switch style {
+	case "ansi":
+		return "\x1b[" + code + "m" + text + "\x1b[0m"
+	case "bash":
+		// \001 and \002 mark non-printing text; \[ \] aren't
+		// interpreted in command substitution output
+		return "\x01\x1b[" + code + "m\x02" + text + "\x01\x1b[0m\x02"
+	case "zsh":
+		return "%{\x1b[" + code + "m%}" + text + "%{\x1b[0m%}"
+	case "tmux":
+		if color == "gray" {
+			color = "brightblack"
+		}
+		return "#[fg=" + strings.ToLower(color) + "]" + text + "#[fg=default]"
+	default:
+		return text
+	}
+}
@@ -30,37 +30,52 @@ var rootCmd = &cobra.Command{
 
 AWS:
   ctx aws                   Interactive profile picker
-  ctx aws <profile>         Switch to profile
+  ctx aws <profile>         Switch to profile (or account ID / ARN)
+  ctx aws -                 Switch back to the previous profile
   ctx aws list   (or -l)    List profiles
   ctx aws current (or -c)   Show current profile
   ctx aws init              Configure SSO
   ctx aws login             SSO login
   ctx aws sync              Sync profiles from SSO
   ctx aws whoami            Show identity
+  ctx aws each -- <cmd>     Run a command in every profile
+  ctx aws verify            Check which profiles work
+  ctx aws which <arn|id>    Find profiles for an account
 
 Azure:
   ctx azure                 Interactive subscription picker
   ctx azure <subscription>  Switch to subscription
+  ctx azure -               Switch back to the previous subscription
   ctx azure list   (or -l)  List subscriptions
   ctx azure current (or -c) Show current subscription
   ctx azure login           Azure login (opens browser)
   ctx azure whoami          Show identity
+  ctx azure each -- <cmd>   Run a command in every subscription
 
 Shortcuts (routes to default_cloud, default: aws):
   ctx                       Interactive picker
   ctx <name>                Switch to profile/subscription
+  ctx -                     Switch back to the previous one
+  ctx history               Pick a recently used context
+  ctx fav add|rm|ls <name>  Manage favorites (pinned in pickers)
   ctx list       (or -l)    List all
   ctx current    (or -c)    Show current
   ctx login                 Login
   ctx whoami                Show identity
   ctx version    (or -v)    Show version
+  ctx prompt                Print active contexts for a shell prompt
+  ctx completion <shell>    Shell completion (incl. profile names)
+
+Per-shell contexts (switching only affects the current shell):
+  eval "$(ctx shell-init zsh)"   (or bash; fish: ctx shell-init fish | source)
 
 Note: -l/-c/-v are shortcuts for list/current/version commands.
       Use ONE or the OTHER, not both together.
 
 Config: ~/.config/cloudctx/config.yaml`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runRoot,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeRootContexts,
+	RunE:              runRoot,
 }
 
 var (
@@ -115,7 +130,7 @@ func init() {
 	rootCmd.AddCommand(createCurrentShortcut())
 	// Note: init and sync are AWS-specific for now
 	rootCmd.AddCommand(createShortcut("init", "Initialize AWS SSO configuration", awsInitCmd))
-	rootCmd.AddCommand(createShortcut("sync", "Sync AWS profiles from SSO", awsSyncCmd))
+	rootCmd.AddCommand(createSyncShortcut())
 }
 
 // createShortcut creates a root-level shortcut to a specific command
@@ -129,6 +144,17 @@ func createShortcut(name, short string, target *cobra.Command) *cobra.Command {
 	}
 }
 
+// createSyncShortcut creates the sync shortcut with the flags of 'aws sync'
+func createSyncShortcut() *cobra.Command {
+	shortcut := createShortcut("sync", "Sync AWS profiles from SSO", awsSyncCmd)
+	shortcut.Flags().BoolVar(&awsSyncDryRun, "dry-run", false, "show the changes without saving ~/.aws/config")
+	shortcut.Flags().BoolVar(&awsSyncDiff, "diff", false, "show the changes to ~/.aws/config before saving")
+	shortcut.Flags().StringVar(&awsSyncInstance, "instance", "", "SSO instance (or \"organizations\") to sync (default: all)")
+	shortcut.Flags().StringArrayVar(&awsSyncInclude, "include", nil, "only sync matching accounts/roles (e.g. 'account=prod-*,role=Admin*')")
+	shortcut.Flags().StringArrayVar(&awsSyncExclude, "exclude", nil, "skip matching accounts/roles (e.g. 'sandbox-*' or 'account_id=1234*')")
+	return shortcut
+}
+
 // createLoginShortcut creates login shortcut that routes to default cloud
 func createLoginShortcut() *cobra.Command {
 	return &cobra.Command{
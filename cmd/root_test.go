@@ -1,6 +1,10 @@
 package cmd
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
 
 func TestVersion(t *testing.T) {
 	// Basic sanity test
@@ -12,3 +16,15 @@ func TestVersion(t *testing.T) {
 	}
 }
 
+
+func TestSyncShortcutFlags(t *testing.T) {
+	shortcut, _, err := rootCmd.Find([]string{"sync"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	awsSyncCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if shortcut.Flags().Lookup(flag.Name) == nil {
+			t.Errorf("'cloudctx sync' is missing --%s of 'cloudctx aws sync'", flag.Name)
+		}
+	})
+}
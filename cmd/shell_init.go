@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// shellNameEnv is set by the shell integration to the calling shell
+	shellNameEnv = "CLOUDCTX_SHELL"
+
+	// shellEnvFileEnv is set by the shell integration to a file the shell
+	// sources after cloudctx exits
+	shellEnvFileEnv = "CLOUDCTX_ENV_FILE"
+)
+
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init <bash|zsh|fish>",
+	Short: "Print shell integration for per-shell contexts",
+	Long: `Print a shell function that makes context switches local to the shell.
+
+By default, switching rewrites [default] in ~/.aws/config and the Azure CLI's
+default subscription, so every terminal and running script follows along.
+With the shell integration loaded, switching only changes the calling shell:
+
+  ctx aws <profile>      exports AWS_PROFILE (and clears static credentials)
+  ctx azure <name>       exports AZURE_SUBSCRIPTION_ID and ARM_SUBSCRIPTION_ID
+
+Use --global on 'ctx aws' or 'ctx azure' to change the global default anyway.
+
+Add one of these to your shell's rc file:
+  eval "$(cloudctx shell-init zsh)"      # ~/.zshrc
+  eval "$(cloudctx shell-init bash)"     # ~/.bashrc
+  cloudctx shell-init fish | source      # ~/.config/fish/config.fish`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	RunE:      runShellInit,
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+}
+
+// posixShellInit wraps cloudctx and ctx in bash and zsh. The status variable
+// isn't named "status", which is read-only in zsh.
+const posixShellInit = `# cloudctx shell integration: context switches only affect this shell
+cloudctx() {
+  local cloudctx_env cloudctx_exit
+  cloudctx_env="$(mktemp)" || return 1
+  CLOUDCTX_SHELL=%s CLOUDCTX_ENV_FILE="$cloudctx_env" command cloudctx "$@"
+  cloudctx_exit=$?
+  if [ -s "$cloudctx_env" ]; then
+    . "$cloudctx_env"
+  fi
+  rm -f "$cloudctx_env"
+  return $cloudctx_exit
+}
+
+ctx() {
+  cloudctx "$@"
+}
+`
+
+const fishShellInit = `# cloudctx shell integration: context switches only affect this shell
+function cloudctx
+    set -l cloudctx_env (mktemp); or return 1
+    env CLOUDCTX_SHELL=fish CLOUDCTX_ENV_FILE=$cloudctx_env cloudctx $argv
+    set -l cloudctx_exit $status
+    if test -s $cloudctx_env
+        source $cloudctx_env
+    end
+    rm -f $cloudctx_env
+    return $cloudctx_exit
+end
+
+function ctx
+    cloudctx $argv
+end
+`
+
+func runShellInit(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash", "zsh":
+		fmt.Printf(posixShellInit, args[0])
+	case "fish":
+		fmt.Print(fishShellInit)
+	default:
+		return fmt.Errorf("unsupported shell '%s' (use bash, zsh or fish)", args[0])
+	}
+	return nil
+}
+
+// shellMode reports whether cloudctx runs under the shell integration, so
+// switches should be exported into the calling shell
+func shellMode() bool {
+	return os.Getenv(shellEnvFileEnv) != ""
+}
+
+// exportToShell writes commands that unset and then set environment
+// variables to the file the shell integration sources
+func exportToShell(unset []string, vars []envVar) error {
+	shell := os.Getenv(shellNameEnv)
+	if shell == "" {
+		shell = detectShell()
+	}
+
+	var b strings.Builder
+	for _, name := range unset {
+		if shell == "fish" {
+			fmt.Fprintf(&b, "set -e %s;\n", name)
+		} else {
+			fmt.Fprintf(&b, "unset %s\n", name)
+		}
+	}
+	exports, err := formatEnv(shell, vars)
+	if err != nil {
+		return err
+	}
+	b.WriteString(exports)
+
+	f, err := os.OpenFile(os.Getenv(shellEnvFileEnv), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write shell environment: %w", err)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write shell environment: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportToShell(t *testing.T) {
+	tests := map[string]string{
+		"zsh":  "unset AWS_ACCESS_KEY_ID\nexport AWS_PROFILE='prod:admin'\n",
+		"fish": "set -e AWS_ACCESS_KEY_ID;\nset -gx AWS_PROFILE 'prod:admin';\n",
+	}
+	for shell, want := range tests {
+		path := filepath.Join(t.TempDir(), "env")
+		t.Setenv(shellNameEnv, shell)
+		t.Setenv(shellEnvFileEnv, path)
+
+		if !shellMode() {
+			t.Fatal("expected shell mode")
+		}
+		if err := exportToShell([]string{"AWS_ACCESS_KEY_ID"}, []envVar{{"AWS_PROFILE", "prod:admin"}}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", shell, got, want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package aws
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/devops-chris/cloudctx/internal/atomicfile"
+)
+
+// expiryWindow treats tokens about to expire as already expired
+const expiryWindow = time.Minute
+
+// ErrNoSSOToken is returned when no cached token exists for the SSO session
+var ErrNoSSOToken = errors.New("no cached SSO token found. Run 'cloudctx aws login' first")
+
+// TokenExpiredError is returned when the cached SSO token has expired
+type TokenExpiredError struct {
+	ExpiresAt time.Time
+}
+
+func (e *TokenExpiredError) Error() string {
+	return fmt.Sprintf("SSO token expired at %s. Run 'cloudctx aws login' to refresh it",
+		e.ExpiresAt.Local().Format(time.RFC1123))
+}
+
+// TokenMismatchError is returned when the cached SSO token was issued by a
+// different SSO portal than the one configured
+type TokenMismatchError struct {
+	Expected string
+	Found    string
+}
+
+func (e *TokenMismatchError) Error() string {
+	return fmt.Sprintf("cached SSO token belongs to %s, not %s. Run 'cloudctx aws login' first",
+		e.Found, e.Expected)
+}
+
+// ssoToken is an SSO token cache entry in the format used by the AWS CLI
+// (~/.aws/sso/cache/<sha1>.json)
+type ssoToken struct {
+	StartURL              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+}
+
+// expiry returns when the access token expires
+func (t *ssoToken) expiry() (time.Time, error) {
+	return parseCacheTime(t.ExpiresAt)
+}
+
+// expired reports whether the access token is expired (or about to be)
+func (t *ssoToken) expired(now time.Time) bool {
+	expiresAt, err := t.expiry()
+	if err != nil {
+		return true
+	}
+	return !now.Add(expiryWindow).Before(expiresAt)
+}
+
+// canRefresh reports whether the token carries a refresh token and a client
+// registration that is still valid
+func (t *ssoToken) canRefresh(now time.Time) bool {
+	if t.RefreshToken == "" || t.ClientID == "" || t.ClientSecret == "" {
+		return false
+	}
+	if t.RegistrationExpiresAt == "" {
+		return true
+	}
+	registrationExpiresAt, err := parseCacheTime(t.RegistrationExpiresAt)
+	return err == nil && now.Before(registrationExpiresAt)
+}
+
+// parseCacheTime parses cache timestamps. Older AWS CLI versions wrote a
+// literal "UTC" suffix instead of "Z".
+func parseCacheTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02T15:04:05UTC", value)
+}
+
+// formatCacheTime formats a timestamp the way the AWS CLI writes it
+func formatCacheTime(t time.Time) string {
+	return t.UTC().Truncate(time.Second).Format(time.RFC3339)
+}
+
+func (p *Provider) ssoCacheDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", "sso", "cache")
+}
+
+// ssoCachePath returns the cache file for a session name or start URL.
+// The AWS CLI names the file after the SHA1 of that key.
+func (p *Provider) ssoCachePath(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(p.ssoCacheDir(), hex.EncodeToString(sum[:])+".json")
+}
+
+// readSSOToken reads and decodes a cache file
+func readSSOToken(path string) (*ssoToken, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var token ssoToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse SSO token cache %s: %w", path, err)
+	}
+	return &token, nil
+}
+
+// loadSSOToken finds the cached token for our sso-session. The AWS CLI keys
+// the cache by session name for sso-session profiles and by start URL for
+// legacy profiles, so both are checked and the first valid token wins. When
+// neither is valid, an expired token is returned together with a
+// *TokenExpiredError, so callers can try a refresh.
+func (p *Provider) loadSSOToken() (*ssoToken, error) {
+	var expiredToken *ssoToken
+	var firstErr error
+	for _, key := range []string{p.sessionName, p.ssoStartURL} {
+		token, err := readSSOToken(p.ssoCachePath(key))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if token.AccessToken == "" {
+			continue
+		}
+		if !sameStartURL(token.StartURL, p.ssoStartURL) {
+			if firstErr == nil {
+				firstErr = &TokenMismatchError{Expected: p.ssoStartURL, Found: token.StartURL}
+			}
+			continue
+		}
+
+		expiresAt, err := token.expiry()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid expiresAt %q in SSO token cache: %w", token.ExpiresAt, err)
+			}
+			continue
+		}
+		if token.expired(time.Now()) {
+			if expiredToken == nil {
+				expiredToken = token
+				firstErr = &TokenExpiredError{ExpiresAt: expiresAt}
+			}
+			continue
+		}
+		return token, nil
+	}
+
+	if expiredToken != nil {
+		return expiredToken, firstErr
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, ErrNoSSOToken
+}
+
+// getAccessToken returns a valid access token for our sso-session,
+// refreshing an expired token with its cached refresh token when possible
+func (p *Provider) getAccessToken() (string, error) {
+	token, err := p.loadSSOToken()
+	var expired *TokenExpiredError
+	if errors.As(err, &expired) && token.canRefresh(time.Now()) {
+		refreshed, refreshErr := p.refreshSSOToken(context.Background(), token)
+		if refreshErr != nil {
+			return "", fmt.Errorf("%w (refresh failed: %v)", err, refreshErr)
+		}
+		return refreshed.AccessToken, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// ensureFreshToken refreshes an expired SSO token ahead of SDK calls that
+// read the cache directly. Errors are ignored; the SDK reports them itself.
+func (p *Provider) ensureFreshToken() {
+	if p.ssoStartURL == "" {
+		return
+	}
+	_, _ = p.getAccessToken()
+}
+
+// sameStartURL compares start URLs ignoring case and trailing slashes
+func sameStartURL(a, b string) bool {
+	normalize := func(u string) string {
+		return strings.TrimRight(strings.ToLower(strings.TrimSpace(u)), "/#")
+	}
+	return normalize(a) == normalize(b)
+}
+
+// writeSSOToken writes the token to the cache file for our sso-session
+func (p *Provider) writeSSOToken(token *ssoToken) error {
+	if err := os.MkdirAll(p.ssoCacheDir(), 0700); err != nil {
+		return fmt.Errorf("failed to create SSO cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode SSO token: %w", err)
+	}
+
+	if err := atomicfile.WriteFile(p.ssoCachePath(p.sessionName), data, 0600); err != nil {
+		return fmt.Errorf("failed to write SSO token cache: %w", err)
+	}
+	return nil
+}
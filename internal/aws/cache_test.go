@@ -0,0 +1,142 @@
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLoadSSOToken(t *testing.T) {
+	const startURL = "https://example.awsapps.com/start"
+	future := formatCacheTime(time.Now().Add(time.Hour))
+	past := formatCacheTime(time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name    string
+		token   *ssoToken
+		wantErr func(error) bool
+	}{
+		{
+			name:    "valid",
+			token:   &ssoToken{StartURL: startURL + "/", AccessToken: "tok", ExpiresAt: future},
+			wantErr: func(err error) bool { return err == nil },
+		},
+		{
+			name:  "expired",
+			token: &ssoToken{StartURL: startURL, AccessToken: "tok", ExpiresAt: past},
+			wantErr: func(err error) bool {
+				var expired *TokenExpiredError
+				return errors.As(err, &expired)
+			},
+		},
+		{
+			name:  "other portal",
+			token: &ssoToken{StartURL: "https://other.awsapps.com/start", AccessToken: "tok", ExpiresAt: future},
+			wantErr: func(err error) bool {
+				var mismatch *TokenMismatchError
+				return errors.As(err, &mismatch) && mismatch.Found == "https://other.awsapps.com/start"
+			},
+		},
+		{
+			name:    "missing",
+			wantErr: func(err error) bool { return errors.Is(err, ErrNoSSOToken) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			p := NewProvider(startURL, "us-east-1", "us-east-1")
+			if tt.token != nil {
+				if err := p.writeSSOToken(tt.token); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			token, err := p.getAccessToken()
+			if !tt.wantErr(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && token != "tok" {
+				t.Errorf("token = %q", token)
+			}
+		})
+	}
+}
+
+func TestLoadSSOTokenFallsBackToStartURL(t *testing.T) {
+	const startURL = "https://example.awsapps.com/start"
+	t.Setenv("HOME", t.TempDir())
+	p := NewProvider(startURL, "us-east-1", "us-east-1")
+
+	// Stale token under the session name, fresh one from a legacy profile login
+	if err := p.writeSSOToken(&ssoToken{StartURL: startURL, AccessToken: "old", ExpiresAt: formatCacheTime(time.Now().Add(-time.Hour))}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(&ssoToken{StartURL: startURL, AccessToken: "tok", ExpiresAt: formatCacheTime(time.Now().Add(time.Hour))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p.ssoCachePath(startURL), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := p.loadSSOToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "tok" {
+		t.Errorf("token = %q, want the start URL's token", token.AccessToken)
+	}
+}
+
+func TestParseCacheTimeLegacyFormat(t *testing.T) {
+	got, err := parseCacheTime("2024-01-02T03:04:05UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGetAccessTokenRefreshesExpiredToken(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := fakeOIDC(t, 0)
+
+	p := NewProvider("https://example.awsapps.com/start", "us-east-1", "us-east-1")
+	p.oidcEndpoint = srv.URL
+	err := p.writeSSOToken(&ssoToken{
+		StartURL:              "https://example.awsapps.com/start",
+		Region:                "us-east-1",
+		AccessToken:           "stale-token",
+		ExpiresAt:             formatCacheTime(time.Now().Add(-time.Hour)),
+		ClientID:              "client-id",
+		ClientSecret:          "client-secret",
+		RegistrationExpiresAt: formatCacheTime(time.Now().Add(24 * time.Hour)),
+		RefreshToken:          "refresh-token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := p.getAccessToken()
+	if err != nil {
+		t.Fatalf("getAccessToken: %v", err)
+	}
+	if token != "refreshed-token" {
+		t.Errorf("token = %q, want refreshed-token", token)
+	}
+
+	// The refreshed token is written back, keeping the refresh token
+	cached, err := p.loadSSOToken()
+	if err != nil {
+		t.Fatalf("loadSSOToken: %v", err)
+	}
+	if cached.AccessToken != "refreshed-token" || cached.RefreshToken != "refresh-token" {
+		t.Errorf("unexpected cache: %+v", cached)
+	}
+}
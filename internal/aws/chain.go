@@ -0,0 +1,136 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// maxChainDepth bounds how far source_profile links are followed
+const maxChainDepth = 10
+
+// ChainedRole describes a role assumed on top of synced SSO profiles. For
+// every synced profile matching Match, Sync writes "<profile>/<Name>" with
+// role_arn set to RoleARN and source_profile set to the synced profile.
+type ChainedRole struct {
+	Match FilterRule
+
+	// Name is appended to the source profile name
+	Name string
+
+	// RoleARN is the role to assume; "{account}" is replaced with the
+	// source profile's account ID
+	RoleARN string
+
+	// ExternalID is passed when assuming the role, if set
+	ExternalID string
+}
+
+// compiledChain is a ChainedRole with its rule compiled
+type compiledChain struct {
+	rule compiledRule
+	ChainedRole
+}
+
+// compileChainedRoles validates and compiles chained role definitions
+func compileChainedRoles(chains []ChainedRole) ([]compiledChain, error) {
+	var compiled []compiledChain
+	for _, chain := range chains {
+		if chain.Name == "" {
+			return nil, fmt.Errorf("chained role for %s has no name", describeRule(chain.Match))
+		}
+		if err := validateProfileName("x/" + chain.Name); err != nil {
+			return nil, fmt.Errorf("chained role %q: %w", chain.Name, err)
+		}
+		if !strings.HasPrefix(chain.RoleARN, "arn:") {
+			return nil, fmt.Errorf("chained role %q: role_arn %q is not an ARN", chain.Name, chain.RoleARN)
+		}
+
+		rule, err := compileRule(chain.Match)
+		if err != nil {
+			return nil, fmt.Errorf("chained role %q: %w", chain.Name, err)
+		}
+		compiled = append(compiled, compiledChain{rule: rule, ChainedRole: chain})
+	}
+	return compiled, nil
+}
+
+// chainProfiles returns the chained profiles for a set of synced profiles.
+// When several definitions produce the same profile name, the first wins.
+func (p *Provider) chainProfiles(chains []compiledChain, profiles []ProfilePlan) []ProfilePlan {
+	var chained []ProfilePlan
+	seen := make(map[string]bool)
+	for _, source := range profiles {
+		for _, chain := range chains {
+			if !chain.rule.matches(source.AccountName, source.AccountID, source.RoleName) {
+				continue
+			}
+
+			name := source.Name + "/" + chain.Name
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			roleARN := strings.ReplaceAll(chain.RoleARN, "{account}", source.AccountID)
+			accountID, roleName := parseRoleARN(roleARN)
+
+			keys := append([]ProfileKey{{"cloudctx_managed", "true"}}, p.ownerKeys()...)
+			keys = append(keys,
+				ProfileKey{"role_arn", roleARN},
+				ProfileKey{"source_profile", source.Name},
+			)
+			if chain.ExternalID != "" {
+				keys = append(keys, ProfileKey{"external_id", chain.ExternalID})
+			}
+			for _, key := range source.Keys {
+				if key.Name == "region" || key.Name == "output" {
+					keys = append(keys, key)
+				}
+			}
+
+			chained = append(chained, ProfilePlan{
+				Name:        name,
+				AccountID:   accountID,
+				AccountName: source.AccountName,
+				RoleName:    roleName,
+				Keys:        keys,
+			})
+		}
+	}
+	return chained
+}
+
+// parseRoleARN extracts the account ID and role name from an IAM role ARN
+// such as "arn:aws:iam::123456789012:role/path/Deploy"
+func parseRoleARN(arn string) (accountID, roleName string) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || !strings.HasPrefix(parts[5], "role/") {
+		return "", ""
+	}
+	resource := strings.TrimPrefix(parts[5], "role/")
+	return parts[4], resource[strings.LastIndex(resource, "/")+1:]
+}
+
+// profileAccountID returns a profile's account ID, from sso_account_id or
+// its role_arn
+func profileAccountID(section *ini.Section) string {
+	if accountID := keyValue(section, "sso_account_id"); accountID != "" {
+		return accountID
+	}
+	accountID, _ := parseRoleARN(keyValue(section, "role_arn"))
+	return accountID
+}
+
+// profileChain follows source_profile links from a profile and returns the
+// profiles it is assumed through, outermost first
+func profileChain(sources map[string]string, name string) []string {
+	var chain []string
+	seen := map[string]bool{name: true}
+	for source := sources[name]; source != "" && !seen[source] && len(chain) < maxChainDepth; source = sources[source] {
+		seen[source] = true
+		chain = append([]string{source}, chain...)
+	}
+	return chain
+}
@@ -0,0 +1,153 @@
+package aws
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
+	"gopkg.in/ini.v1"
+)
+
+func TestSyncGeneratesChainedRoles(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod"), account("222222222222", "Sandbox")},
+		roles:     map[string][]string{"111111111111": {"Admin", "ReadOnly"}, "222222222222": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+	p.WithSyncOptions(SyncOptions{
+		ChainedRoles: []ChainedRole{{
+			Match:   FilterRule{Account: "prod", Role: "Admin"},
+			Name:    "deploy",
+			RoleARN: "arn:aws:iam::{account}:role/ci/Deploy",
+		}},
+	})
+
+	existing := "[profile stale:admin/deploy]\ncloudctx_managed = true\ncloudctx_sso_session = cloudctx-cli\nrole_arn = arn:aws:iam::999999999999:role/Deploy\nsource_profile = stale:admin\n"
+	if err := os.WriteFile(p.awsConfigPath(), []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ini.Load(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.GetSection("profile stale:admin/deploy"); err == nil {
+		t.Error("stale chained profile was not removed")
+	}
+	section, err := cfg.GetSection("profile prod:admin/deploy")
+	if err != nil {
+		t.Fatal("chained profile not written")
+	}
+	if got := section.Key("role_arn").String(); got != "arn:aws:iam::111111111111:role/ci/Deploy" {
+		t.Errorf("role_arn = %q", got)
+	}
+	if got := section.Key("source_profile").String(); got != "prod:admin" {
+		t.Errorf("source_profile = %q", got)
+	}
+	for _, name := range []string{"profile prod:readonly/deploy", "profile sandbox:admin/deploy"} {
+		if _, err := cfg.GetSection(name); err == nil {
+			t.Errorf("unexpected chained profile %s", name)
+		}
+	}
+
+	contexts, err := p.ListContexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ctx := range contexts {
+		if ctx.Name != "prod:admin/deploy" {
+			continue
+		}
+		if ctx.AccountID != "111111111111" || ctx.AccountName != "Prod" || ctx.Role != "Deploy" || !ctx.Managed {
+			t.Errorf("chained context = %+v", ctx)
+		}
+		if !reflect.DeepEqual(ctx.Chain, []string{"prod:admin"}) {
+			t.Errorf("chain = %v", ctx.Chain)
+		}
+		return
+	}
+	t.Error("chained profile not listed")
+}
+
+func TestSyncKeepsChainedProfilesOfFailedAccounts(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod"), account("333333333333", "Broken")},
+		roles:     map[string][]string{"111111111111": {"Admin"}},
+		failures:  map[string]error{"333333333333": errors.New("boom")},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+	p.WithSyncOptions(SyncOptions{
+		ChainedRoles: []ChainedRole{{
+			Match:   FilterRule{Role: "Admin"},
+			Name:    "deploy",
+			RoleARN: "arn:aws:iam::999999999999:role/Deploy",
+		}},
+	})
+
+	// The chained role lives in a tooling account that did sync fine
+	existing := "[profile broken:admin]\ncloudctx_managed = true\nsso_session = cloudctx-cli\nsso_account_id = 333333333333\nsso_role_name = Admin\n\n" +
+		"[profile broken:admin/deploy]\ncloudctx_managed = true\ncloudctx_sso_session = cloudctx-cli\nrole_arn = arn:aws:iam::999999999999:role/Deploy\nsource_profile = broken:admin\n"
+	if err := os.WriteFile(p.awsConfigPath(), []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ini.Load(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"broken:admin", "broken:admin/deploy", "prod:admin/deploy"} {
+		if _, err := cfg.GetSection("profile " + name); err != nil {
+			t.Errorf("missing profile %s", name)
+		}
+	}
+}
+
+func TestProfileChain(t *testing.T) {
+	sources := map[string]string{
+		"deploy": "admin",
+		"admin":  "base",
+		"loop-a": "loop-b",
+		"loop-b": "loop-a",
+		"static": "static",
+	}
+
+	tests := map[string][]string{
+		"deploy": {"base", "admin"},
+		"loop-a": {"loop-b"},
+		"static": nil,
+		"base":   nil,
+	}
+	for name, want := range tests {
+		if got := profileChain(sources, name); !reflect.DeepEqual(got, want) {
+			t.Errorf("profileChain(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseRoleARN(t *testing.T) {
+	tests := map[string][2]string{
+		"arn:aws:iam::123456789012:role/Deploy":    {"123456789012", "Deploy"},
+		"arn:aws:iam::123456789012:role/ci/Deploy": {"123456789012", "Deploy"},
+		"arn:aws-cn:iam::123456789012:role/Admin":  {"123456789012", "Admin"},
+		"arn:aws:iam::123456789012:user/alice":     {"", ""},
+		"not-an-arn":                               {"", ""},
+	}
+	for arn, want := range tests {
+		accountID, role := parseRoleARN(arn)
+		if accountID != want[0] || role != want[1] {
+			t.Errorf("parseRoleARN(%s) = %s, %s; want %s, %s", arn, accountID, role, want[0], want[1])
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package aws
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/devops-chris/cloudctx/internal/atomicfile"
+	"github.com/devops-chris/cloudctx/internal/config"
+)
+
+const (
+	// credentialCacheDir holds cached role credentials, under the config dir
+	credentialCacheDir = "aws_credentials"
+
+	// credentialRefreshWindow is how long before expiry cached credentials
+	// are replaced, so callers never receive credentials about to expire
+	credentialRefreshWindow = 5 * time.Minute
+)
+
+// CachedCredentials resolves a profile's credentials like ResolveCredentials,
+// reusing credentials cached by an earlier call until shortly before they
+// expire. Static keys are never cached.
+func (p *Provider) CachedCredentials(name string) (*Credentials, error) {
+	path := credentialCachePath(name)
+	if cached, err := readCachedCredentials(path); err == nil &&
+		cached.Key == p.credentialCacheKey(name) &&
+		time.Until(cached.Credentials.Expiration) > credentialRefreshWindow {
+		return &cached.Credentials, nil
+	}
+
+	creds, err := p.ResolveCredentials(name)
+	if err != nil {
+		return nil, err
+	}
+	if !creds.Expiration.IsZero() {
+		// Resolving may have refreshed the SSO token, so the key is computed
+		// again. The cache only saves round trips; failing to write it isn't
+		// fatal.
+		_ = writeCachedCredentials(path, &cachedCredentials{Key: p.credentialCacheKey(name), Credentials: *creds})
+	}
+	return creds, nil
+}
+
+// cachedCredentials are credentials cached for a profile, with the key
+// (see credentialCacheKey) they were resolved under
+type cachedCredentials struct {
+	Key         string
+	Credentials Credentials
+}
+
+// credentialCachePath returns the cache file for a profile
+func credentialCachePath(name string) string {
+	sum := sha1.Sum([]byte(name))
+	return filepath.Join(config.ConfigDir(), credentialCacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// credentialCacheKey identifies what a profile's credentials are resolved
+// from: the profile and its source profiles' account, role and session,
+// plus the SSO access token in use. Cached credentials are only reused while
+// the key matches, so editing a profile or logging in again never serves
+// credentials for the old account, role or session.
+func (p *Provider) credentialCacheKey(name string) string {
+	parts := []string{name}
+	for depth := 0; name != "" && depth <= maxChainDepth; depth++ {
+		profile, err := p.loadProfileConfig(name)
+		if err != nil {
+			break
+		}
+		parts = append(parts, profile.kind,
+			profile.value("sso_account_id"), profile.value("sso_role_name"),
+			profile.value("role_arn"), profile.value("role_session_name"), profile.value("external_id"))
+
+		if profile.kind == ProfileTypeSSO {
+			if session, err := p.profileSession(profile); err == nil {
+				parts = append(parts, session.sessionName)
+				if token, err := session.loadSSOToken(); token != nil && err == nil {
+					parts = append(parts, token.AccessToken)
+				}
+			}
+			break
+		}
+
+		source := profile.value("source_profile")
+		if source == name {
+			break
+		}
+		parts = append(parts, source)
+		name = source
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func readCachedCredentials(path string) (*cachedCredentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cached cachedCredentials
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+func writeCachedCredentials(path string, cached *cachedCredentials) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, data, 0600)
+}
+
+// credentialProcessCommand returns the credential_process value that runs
+// command's credential-process helper for a profile
+func credentialProcessCommand(command, profile string) string {
+	if strings.ContainsAny(profile, " \t\"'\\") {
+		profile = `"` + strings.ReplaceAll(profile, `"`, `\"`) + `"`
+	}
+	return fmt.Sprintf("%s aws credential-process --profile %s", command, profile)
+}
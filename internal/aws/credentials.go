@@ -0,0 +1,327 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"gopkg.in/ini.v1"
+)
+
+// defaultCredentialsRegion is used for STS calls when a profile has no region
+const defaultCredentialsRegion = "us-east-1"
+
+// Credentials are resolved AWS credentials for a profile
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Expiration is zero for long-lived static keys
+	Expiration time.Time
+
+	// Region is the profile's region, if set
+	Region string
+}
+
+// profileConfig is a profile's settings from ~/.aws/config and ~/.aws/credentials
+type profileConfig struct {
+	name   string
+	kind   string       // see profileType
+	config *ini.Section // nil if the profile is only in ~/.aws/credentials
+	creds  *ini.Section // nil if the profile has no credentials-file section
+	awsCfg *ini.File
+}
+
+// value returns a key from the config section, falling back to the
+// credentials section
+func (c *profileConfig) value(name string) string {
+	for _, section := range []*ini.Section{c.config, c.creds} {
+		if section == nil {
+			continue
+		}
+		if value := keyValue(section, name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ResolveCredentials resolves a profile into credentials: SSO profiles via
+// the SSO portal's GetRoleCredentials, role_arn profiles by assuming the
+// role with their source profile's credentials, and static keys as they
+// are. Other profile types are resolved by the AWS SDK. An empty name
+// resolves the current profile.
+func (p *Provider) ResolveCredentials(name string) (*Credentials, error) {
+	if name == "" {
+		current, err := p.CurrentContext()
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, fmt.Errorf("no AWS profile set")
+		}
+		name = current.Name
+	}
+	return p.resolveCredentials(context.Background(), name, 0)
+}
+
+func (p *Provider) resolveCredentials(ctx context.Context, name string, depth int) (*Credentials, error) {
+	if depth > maxChainDepth {
+		return nil, fmt.Errorf("profile %s: source_profile chain is too long or circular", name)
+	}
+
+	profile, err := p.loadProfileConfig(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var creds *Credentials
+	switch profile.kind {
+	case ProfileTypeSSO:
+		creds, err = p.resolveSSOCredentials(ctx, profile)
+	case ProfileTypeAssumeRole:
+		creds, err = p.resolveAssumeRoleCredentials(ctx, profile, depth)
+	case ProfileTypeStaticKeys:
+		creds = &Credentials{
+			AccessKeyID:     profile.value("aws_access_key_id"),
+			SecretAccessKey: profile.value("aws_secret_access_key"),
+			SessionToken:    profile.value("aws_session_token"),
+		}
+	default:
+		creds, err = p.resolveSDKCredentials(ctx, profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if creds.Region == "" {
+		creds.Region = profile.value("region")
+	}
+	return creds, nil
+}
+
+// loadProfileConfig finds a profile in ~/.aws/config or ~/.aws/credentials
+func (p *Provider) loadProfileConfig(name string) (*profileConfig, error) {
+	awsCfg, err := ini.Load(p.awsConfigPath())
+	if err != nil {
+		awsCfg = ini.Empty()
+	}
+	awsCreds, err := ini.Load(p.awsCredentialsPath())
+	if err != nil {
+		awsCreds = nil
+	}
+
+	profile := &profileConfig{name: name, awsCfg: awsCfg, creds: credentialsSection(awsCreds, name)}
+	sectionName := "profile " + name
+	if name == "default" {
+		sectionName = "default"
+	}
+	if section, err := awsCfg.GetSection(sectionName); err == nil {
+		profile.config = section
+	}
+	if profile.config == nil && profile.creds == nil {
+		return nil, fmt.Errorf("profile '%s' not found in config or credentials", name)
+	}
+
+	profile.kind = profileType(profile.config, profile.creds)
+	return profile, nil
+}
+
+// resolveSSOCredentials exchanges the cached SSO token of the profile's
+// sso-session (or legacy sso_start_url) for role credentials
+func (p *Provider) resolveSSOCredentials(ctx context.Context, profile *profileConfig) (*Credentials, error) {
+	session, err := p.profileSession(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	accountID := profile.value("sso_account_id")
+	roleName := profile.value("sso_role_name")
+	if accountID == "" || roleName == "" {
+		return nil, fmt.Errorf("profile %s: sso_account_id and sso_role_name are required", profile.name)
+	}
+
+	accessToken, err := session.getAccessToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSO access token (try 'cloudctx aws login' first): %w", err)
+	}
+
+	client, err := session.newSSOClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.GetRoleCredentials(ctx, &sso.GetRoleCredentialsInput{
+		AccessToken: aws.String(accessToken),
+		AccountId:   aws.String(accountID),
+		RoleName:    aws.String(roleName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role credentials for %s: %w", profile.name, err)
+	}
+
+	roleCreds := output.RoleCredentials
+	return &Credentials{
+		AccessKeyID:     aws.ToString(roleCreds.AccessKeyId),
+		SecretAccessKey: aws.ToString(roleCreds.SecretAccessKey),
+		SessionToken:    aws.ToString(roleCreds.SessionToken),
+		Expiration:      time.UnixMilli(roleCreds.Expiration).UTC(),
+	}, nil
+}
+
+// profileSession returns a provider for the sso-session (or legacy
+// sso_start_url) of an SSO profile, so tokens of other SSO instances and of
+// sessions cloudctx doesn't manage are found (and refreshed) too
+func (p *Provider) profileSession(profile *profileConfig) (*Provider, error) {
+	session := *p
+	if sessionName := profile.value("sso_session"); sessionName != "" {
+		section, err := profile.awsCfg.GetSection("sso-session " + sessionName)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: sso-session %s not found in ~/.aws/config", profile.name, sessionName)
+		}
+		session.sessionName = sessionName
+		session.ssoStartURL = keyValue(section, "sso_start_url")
+		session.ssoRegion = keyValue(section, "sso_region")
+	} else {
+		// Legacy profiles: the AWS CLI keys the token cache by start URL
+		session.ssoStartURL = profile.value("sso_start_url")
+		session.ssoRegion = profile.value("sso_region")
+		session.sessionName = session.ssoStartURL
+	}
+	return &session, nil
+}
+
+// ensureFreshProfileToken refreshes an expired token of the SSO session a
+// profile uses, directly or through its source_profile chain, ahead of SDK
+// calls that read the cache. Errors are ignored; the SDK reports them itself.
+func (p *Provider) ensureFreshProfileToken(name string) {
+	if session := p.profileTokenSession(name); session != nil {
+		session.ensureFreshToken()
+	}
+}
+
+// profileTokenSession returns a provider for the SSO session a profile gets
+// its token from, directly or through its source_profile chain, or nil if it
+// uses none
+func (p *Provider) profileTokenSession(name string) *Provider {
+	for depth := 0; name != "" && depth <= maxChainDepth; depth++ {
+		profile, err := p.loadProfileConfig(name)
+		if err != nil {
+			return nil
+		}
+		if profile.kind == ProfileTypeSSO {
+			session, err := p.profileSession(profile)
+			if err != nil {
+				return nil
+			}
+			return session
+		}
+		name = profile.value("source_profile")
+	}
+	return nil
+}
+
+// resolveAssumeRoleCredentials resolves the source profile and assumes the
+// profile's role with its credentials
+func (p *Provider) resolveAssumeRoleCredentials(ctx context.Context, profile *profileConfig, depth int) (*Credentials, error) {
+	if profile.value("mfa_serial") != "" {
+		return nil, fmt.Errorf("profile %s: roles requiring MFA are not supported", profile.name)
+	}
+
+	source := profile.value("source_profile")
+	if source == "" {
+		// credential_source (Environment, Ec2InstanceMetadata, ...) is left to the SDK
+		return p.resolveSDKCredentials(ctx, profile)
+	}
+
+	var sourceCreds *Credentials
+	var err error
+	if source == profile.name {
+		// A profile may use its own static keys as the source
+		sourceCreds = &Credentials{
+			AccessKeyID:     profile.value("aws_access_key_id"),
+			SecretAccessKey: profile.value("aws_secret_access_key"),
+			SessionToken:    profile.value("aws_session_token"),
+		}
+	} else if sourceCreds, err = p.resolveCredentials(ctx, source, depth+1); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", profile.name, err)
+	}
+
+	region := profile.value("region")
+	if region == "" {
+		region = defaultCredentialsRegion
+	}
+	client := sts.New(sts.Options{
+		Region: region,
+		Credentials: credentials.NewStaticCredentialsProvider(
+			sourceCreds.AccessKeyID, sourceCreds.SecretAccessKey, sourceCreds.SessionToken),
+	}, func(o *sts.Options) {
+		if p.stsEndpoint != "" {
+			o.BaseEndpoint = aws.String(p.stsEndpoint)
+		}
+	})
+
+	sessionName := profile.value("role_session_name")
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("cloudctx-%d", time.Now().Unix())
+	}
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(profile.value("role_arn")),
+		RoleSessionName: aws.String(sessionName),
+	}
+	if externalID := profile.value("external_id"); externalID != "" {
+		input.ExternalId = aws.String(externalID)
+	}
+	if duration := profile.value("duration_seconds"); duration != "" {
+		seconds, err := strconv.Atoi(duration)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: invalid duration_seconds %q", profile.name, duration)
+		}
+		input.DurationSeconds = aws.Int32(int32(seconds))
+	}
+	output, err := client.AssumeRole(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume role %s: %w", profile.value("role_arn"), err)
+	}
+	return &Credentials{
+		AccessKeyID:     aws.ToString(output.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(output.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(output.Credentials.SessionToken),
+		Expiration:      aws.ToTime(output.Credentials.Expiration),
+	}, nil
+}
+
+// resolveSDKCredentials lets the AWS SDK resolve a profile cloudctx doesn't
+// handle itself (credential_process, web identity, credential_source)
+func (p *Provider) resolveSDKCredentials(ctx context.Context, profile *profileConfig) (*Credentials, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigFiles([]string{p.awsConfigPath()}),
+		config.WithSharedCredentialsFiles([]string{p.awsCredentialsPath()}),
+		config.WithSharedConfigProfile(profile.name),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config for profile %s: %w", profile.name, err)
+	}
+
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve credentials for profile %s: %w", profile.name, err)
+	}
+
+	resolved := &Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Region:          cfg.Region,
+	}
+	if creds.CanExpire {
+		resolved.Expiration = creds.Expires
+	}
+	return resolved, nil
+}
@@ -0,0 +1,183 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
+	"gopkg.in/ini.v1"
+)
+
+// fakeSTS is a minimal STS query endpoint answering AssumeRole
+func fakeSTS(t *testing.T, wantAccessKey string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Form.Get("Action") != "AssumeRole" {
+			t.Errorf("unexpected action %q", r.Form.Get("Action"))
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), wantAccessKey) {
+			t.Errorf("AssumeRole not signed with source credentials: %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASIAASSUMED</AccessKeyId>
+      <SecretAccessKey>assumed-secret</SecretAccessKey>
+      <SessionToken>assumed-session</SessionToken>
+      <Expiration>2030-01-01T01:00:00Z</Expiration>
+    </Credentials>
+    <AssumedRoleUser>
+      <Arn>%s</Arn>
+      <AssumedRoleId>AROA:cloudctx</AssumedRoleId>
+    </AssumedRoleUser>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`, r.Form.Get("RoleArn"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolveCredentials(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod")},
+		roles:     map[string][]string{"111111111111": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+	p.stsEndpoint = fakeSTS(t, "ASIA111111111111").URL
+
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := os.ReadFile(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = append(config, "\n[profile deploy]\nrole_arn = arn:aws:iam::111111111111:role/Deploy\nsource_profile = prod:admin\nregion = us-west-2\n"...)
+	if err := os.WriteFile(p.awsConfigPath(), config, 0600); err != nil {
+		t.Fatal(err)
+	}
+	creds := "[keys]\naws_access_key_id = AKIDSTATIC\naws_secret_access_key = static-secret\n"
+	if err := os.WriteFile(p.awsCredentialsPath(), []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile string
+		want    Credentials
+	}{
+		{"prod:admin", Credentials{
+			AccessKeyID:     "ASIA111111111111",
+			SecretAccessKey: "secret-Admin",
+			SessionToken:    "session",
+			Expiration:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			Region:          "eu-west-1",
+		}},
+		{"deploy", Credentials{
+			AccessKeyID:     "ASIAASSUMED",
+			SecretAccessKey: "assumed-secret",
+			SessionToken:    "assumed-session",
+			Expiration:      time.Date(2030, 1, 1, 1, 0, 0, 0, time.UTC),
+			Region:          "us-west-2",
+		}},
+		{"keys", Credentials{
+			AccessKeyID:     "AKIDSTATIC",
+			SecretAccessKey: "static-secret",
+		}},
+	}
+	for _, tt := range tests {
+		got, err := p.ResolveCredentials(tt.profile)
+		if err != nil {
+			t.Errorf("%s: %v", tt.profile, err)
+			continue
+		}
+		if !got.Expiration.Equal(tt.want.Expiration) {
+			t.Errorf("%s: expiration = %v, want %v", tt.profile, got.Expiration, tt.want.Expiration)
+		}
+		got.Expiration, tt.want.Expiration = time.Time{}, time.Time{}
+		if *got != tt.want {
+			t.Errorf("%s: credentials = %+v, want %+v", tt.profile, *got, tt.want)
+		}
+	}
+
+	if _, err := p.ResolveCredentials("missing"); err == nil {
+		t.Error("expected an error for a missing profile")
+	}
+}
+
+func TestCachedCredentials(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod Web")},
+		roles:     map[string][]string{"111111111111": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+	p.WithSyncOptions(SyncOptions{CredentialProcess: "cloudctx"})
+
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	contexts, err := p.ListContexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contexts) != 1 || contexts[0].Type != ProfileTypeSSO {
+		t.Fatalf("contexts = %+v", contexts)
+	}
+	config, err := os.ReadFile(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config), "credential_process = cloudctx aws credential-process --profile prod-web:admin") {
+		t.Errorf("credential_process not written:\n%s", config)
+	}
+
+	for i := 0; i < 2; i++ {
+		creds, err := p.CachedCredentials("prod-web:admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds.AccessKeyID != "ASIA111111111111" {
+			t.Errorf("access key = %q", creds.AccessKeyID)
+		}
+	}
+	if calls := client.callCount["credentials:111111111111"]; calls != 1 {
+		t.Errorf("GetRoleCredentials called %d times, want 1 (second call cached)", calls)
+	}
+
+	// Pointing the profile at another role must not reuse the cached credentials
+	awsCfg, err := ini.Load(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	awsCfg.Section("profile prod-web:admin").Key("sso_role_name").SetValue("ReadOnly")
+	if err := awsCfg.SaveTo(p.awsConfigPath()); err != nil {
+		t.Fatal(err)
+	}
+	creds, err := p.CachedCredentials("prod-web:admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.SecretAccessKey != "secret-ReadOnly" {
+		t.Errorf("secret key = %q, want credentials for the new role", creds.SecretAccessKey)
+	}
+
+	info, err := os.Stat(credentialCachePath("prod-web:admin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("cache file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,127 @@
+package aws
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// SyncDiff describes how a sync plan would change ~/.aws/config
+type SyncDiff struct {
+	Added   []ProfileChange
+	Removed []ProfileChange
+	Changed []ProfileChange
+}
+
+// ProfileChange is a profile section that would be added, removed or changed
+type ProfileChange struct {
+	Name string
+	Keys []KeyChange
+}
+
+// KeyChange is a single key that differs. Old is empty for new keys and New
+// is empty for removed keys.
+type KeyChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// Empty reports whether the plan would leave ~/.aws/config unchanged
+func (d *SyncDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSync compares a sync plan against the current ~/.aws/config without
+// saving anything
+func (p *Provider) DiffSync(plan *SyncPlan) (*SyncDiff, error) {
+	awsConfigPath := p.awsConfigPath()
+	before, err := ini.Load(awsConfigPath)
+	if err != nil {
+		before = ini.Empty()
+	}
+	after, err := ini.Load(awsConfigPath)
+	if err != nil {
+		after = ini.Empty()
+	}
+
+	p.applyPlan(after, plan)
+
+	oldProfiles := profileSections(before)
+	newProfiles := profileSections(after)
+
+	diff := &SyncDiff{}
+	for _, name := range sortedKeys(newProfiles) {
+		oldSection, existed := oldProfiles[name]
+		if !existed {
+			diff.Added = append(diff.Added, ProfileChange{Name: name, Keys: keyChanges(nil, newProfiles[name])})
+			continue
+		}
+		if changes := keyChanges(oldSection, newProfiles[name]); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, ProfileChange{Name: name, Keys: changes})
+		}
+	}
+	for _, name := range sortedKeys(oldProfiles) {
+		if _, exists := newProfiles[name]; !exists {
+			diff.Removed = append(diff.Removed, ProfileChange{Name: name, Keys: keyChanges(oldProfiles[name], nil)})
+		}
+	}
+
+	return diff, nil
+}
+
+// profileSections indexes the [profile ...] sections of a config by profile name
+func profileSections(f *ini.File) map[string]*ini.Section {
+	sections := make(map[string]*ini.Section)
+	for _, section := range f.Sections() {
+		if strings.HasPrefix(section.Name(), "profile ") {
+			sections[strings.TrimPrefix(section.Name(), "profile ")] = section
+		}
+	}
+	return sections
+}
+
+// keyChanges lists the keys that differ between two sections (either may be nil)
+func keyChanges(oldSection, newSection *ini.Section) []KeyChange {
+	oldKeys := sectionKeys(oldSection)
+	newKeys := sectionKeys(newSection)
+
+	var changes []KeyChange
+	if newSection != nil {
+		for _, key := range newSection.Keys() {
+			name := key.Name()
+			if oldValue, ok := oldKeys[name]; !ok || oldValue != key.Value() {
+				changes = append(changes, KeyChange{Key: name, Old: oldValue, New: key.Value()})
+			}
+		}
+	}
+	if oldSection != nil {
+		for _, key := range oldSection.Keys() {
+			if _, ok := newKeys[key.Name()]; !ok {
+				changes = append(changes, KeyChange{Key: key.Name(), Old: key.Value()})
+			}
+		}
+	}
+	return changes
+}
+
+func sectionKeys(section *ini.Section) map[string]string {
+	keys := make(map[string]string)
+	if section == nil {
+		return keys
+	}
+	for _, key := range section.Keys() {
+		keys[key.Name()] = key.Value()
+	}
+	return keys
+}
+
+func sortedKeys(m map[string]*ini.Section) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
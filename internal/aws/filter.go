@@ -0,0 +1,209 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FilterRule selects accounts and roles during sync. Each field is a glob
+// (e.g. "sandbox-*") or, when wrapped in slashes, a regular expression
+// (e.g. "/^sbx-[0-9]+$/"). A rule matches when all non-empty fields match;
+// matching is case-insensitive.
+type FilterRule struct {
+	Account   string
+	AccountID string
+	Role      string
+}
+
+// ParseFilterRule parses a rule from the command line, in the form
+// "account=prod-*,role=Admin*". A bare pattern matches the account name.
+// Commas inside /regex/ patterns don't separate fields.
+func ParseFilterRule(s string) (FilterRule, error) {
+	var rule FilterRule
+	for _, part := range splitFilterFields(s) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, pattern, found := strings.Cut(part, "=")
+		if !found {
+			key, pattern = "account", part
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "account", "account_name":
+			rule.Account = pattern
+		case "account_id", "id":
+			rule.AccountID = pattern
+		case "role", "role_name":
+			rule.Role = pattern
+		default:
+			return rule, fmt.Errorf("unknown filter field %q in %q (use account, account_id or role)", key, s)
+		}
+	}
+
+	if rule == (FilterRule{}) {
+		return rule, fmt.Errorf("empty filter %q", s)
+	}
+	return rule, nil
+}
+
+// splitFilterFields splits a rule at commas that are outside /regex/ patterns
+func splitFilterFields(s string) []string {
+	var fields []string
+	start := 0
+	inRegex := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if inRegex {
+				i++ // Escaped character, e.g. \/ or \,
+			}
+		case '/':
+			if inRegex {
+				inRegex = false
+			} else if strings.TrimSpace(s[start:i]) == "" || strings.HasSuffix(strings.TrimSpace(s[start:i]), "=") {
+				// A slash opening a field's pattern starts a regex
+				inRegex = true
+			}
+		case ',':
+			if !inRegex {
+				fields = append(fields, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(fields, s[start:])
+}
+
+// compiledRule is a FilterRule with its patterns compiled
+type compiledRule struct {
+	account   *regexp.Regexp
+	accountID *regexp.Regexp
+	role      *regexp.Regexp
+}
+
+// syncFilter decides which accounts and roles Sync generates profiles for
+type syncFilter struct {
+	include []compiledRule
+	exclude []compiledRule
+}
+
+// newSyncFilter compiles include and exclude rules
+func newSyncFilter(include, exclude []FilterRule) (*syncFilter, error) {
+	f := &syncFilter{}
+	for _, rule := range include {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include filter: %w", err)
+		}
+		f.include = append(f.include, compiled)
+	}
+	for _, rule := range exclude {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude filter: %w", err)
+		}
+		f.exclude = append(f.exclude, compiled)
+	}
+	return f, nil
+}
+
+// skipsAccount reports whether no role in an account can pass the filter,
+// so its roles don't need to be listed at all
+func (f *syncFilter) skipsAccount(accountName, accountID string) bool {
+	for _, rule := range f.exclude {
+		if rule.role == nil && rule.matchesAccount(accountName, accountID) {
+			return true
+		}
+	}
+
+	if len(f.include) == 0 {
+		return false
+	}
+	for _, rule := range f.include {
+		if rule.matchesAccount(accountName, accountID) {
+			return false
+		}
+	}
+	return true
+}
+
+// allows reports whether a profile should be generated for an account/role
+func (f *syncFilter) allows(accountName, accountID, roleName string) bool {
+	for _, rule := range f.exclude {
+		if rule.matches(accountName, accountID, roleName) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, rule := range f.include {
+		if rule.matches(accountName, accountID, roleName) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r compiledRule) matchesAccount(accountName, accountID string) bool {
+	return matchPattern(r.account, accountName) && matchPattern(r.accountID, accountID)
+}
+
+func (r compiledRule) matches(accountName, accountID, roleName string) bool {
+	return r.matchesAccount(accountName, accountID) && matchPattern(r.role, roleName)
+}
+
+// matchPattern matches a compiled pattern; a nil pattern matches anything
+func matchPattern(re *regexp.Regexp, value string) bool {
+	return re == nil || re.MatchString(value)
+}
+
+func compileRule(rule FilterRule) (compiledRule, error) {
+	var compiled compiledRule
+	var err error
+	if compiled.account, err = compilePattern(rule.Account); err != nil {
+		return compiled, err
+	}
+	if compiled.accountID, err = compilePattern(rule.AccountID); err != nil {
+		return compiled, err
+	}
+	if compiled.role, err = compilePattern(rule.Role); err != nil {
+		return compiled, err
+	}
+	return compiled, nil
+}
+
+// compilePattern compiles a glob or /regex/ pattern. Empty patterns compile to nil.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %w", pattern, err)
+		}
+		return re, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
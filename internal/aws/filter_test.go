@@ -0,0 +1,61 @@
+package aws
+
+import "testing"
+
+func TestSyncFilter(t *testing.T) {
+	include := []FilterRule{{Account: "prod-*"}, {Account: "/^data-[0-9]+$/", Role: "ReadOnly"}}
+	exclude := []FilterRule{{AccountID: "999*"}, {Role: "*Billing*"}}
+
+	f, err := newSyncFilter(include, exclude)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		account, id, role string
+		skipAccount       bool
+		allowed           bool
+	}{
+		{"Prod-Web", "111111111111", "Admin", false, true},
+		{"prod-web", "111111111111", "BillingAdmin", false, false},
+		{"prod-old", "999999999999", "Admin", true, false},
+		{"data-42", "222222222222", "ReadOnly", false, true},
+		{"data-42", "222222222222", "Admin", false, false},
+		{"sandbox-1", "333333333333", "Admin", true, false},
+	}
+	for _, tt := range tests {
+		if got := f.skipsAccount(tt.account, tt.id); got != tt.skipAccount {
+			t.Errorf("skipsAccount(%s, %s) = %v, want %v", tt.account, tt.id, got, tt.skipAccount)
+		}
+		if got := f.allows(tt.account, tt.id, tt.role); got != tt.allowed {
+			t.Errorf("allows(%s, %s, %s) = %v, want %v", tt.account, tt.id, tt.role, got, tt.allowed)
+		}
+	}
+}
+
+func TestParseFilterRule(t *testing.T) {
+	tests := map[string]FilterRule{
+		"sandbox-*":                    {Account: "sandbox-*"},
+		"account=prod-*,role=Admin*":   {Account: "prod-*", Role: "Admin*"},
+		"account_id=1234*":             {AccountID: "1234*"},
+		"role=/^(Admin|PowerUser).*$/": {Role: "/^(Admin|PowerUser).*$/"},
+		`/^sbx-\d{1,2}$/`:              {Account: `/^sbx-\d{1,2}$/`},
+		`account=/^a{1,2}\/b$/,role=x`: {Account: `/^a{1,2}\/b$/`, Role: "x"},
+	}
+	for in, want := range tests {
+		got, err := ParseFilterRule(in)
+		if err != nil {
+			t.Errorf("ParseFilterRule(%q): %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseFilterRule(%q) = %+v, want %+v", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"", "region=eu-*"} {
+		if _, err := ParseFilterRule(in); err == nil {
+			t.Errorf("expected ParseFilterRule(%q) to fail", in)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package aws
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/devops-chris/cloudctx/internal/atomicfile"
+	"github.com/devops-chris/cloudctx/internal/provider"
+)
+
+// syncHistoryFile is the JSON-lines file of past sync results in the state dir
+const syncHistoryFile = "aws_sync_history.jsonl"
+
+// maxSyncHistory is the number of sync results kept in the history
+const maxSyncHistory = 100
+
+// appendSyncHistory records a sync result, keeping the newest maxSyncHistory entries
+func (p *Provider) appendSyncHistory(result *provider.SyncResult) error {
+	history, err := p.SyncHistory()
+	if err != nil {
+		return err
+	}
+
+	history = append(history, *result)
+	if len(history) > maxSyncHistory {
+		history = history[len(history)-maxSyncHistory:]
+	}
+
+	stateDir := p.stateDir()
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range history {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return atomicfile.WriteFile(filepath.Join(stateDir, syncHistoryFile), buf.Bytes(), 0644)
+}
+
+// SyncHistory returns past sync results, oldest first. Lines that can't be
+// parsed are skipped.
+func (p *Provider) SyncHistory() ([]provider.SyncResult, error) {
+	f, err := os.Open(filepath.Join(p.stateDir(), syncHistoryFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var history []provider.SyncResult
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry provider.SyncResult
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Skip lines that can't be parsed, so one bad line doesn't stop
+			// every later sync from being recorded
+			continue
+		}
+		history = append(history, entry)
+	}
+	return history, scanner.Err()
+}
+
+// AccountChanges returns the accounts that appeared and disappeared between
+// two sync results
+func AccountChanges(previous, current *provider.SyncResult) (added, removed []provider.SyncedAccount) {
+	seen := make(map[string]bool)
+	for _, account := range previous.Accounts {
+		seen[account.ID] = true
+	}
+	for _, account := range current.Accounts {
+		if !seen[account.ID] {
+			added = append(added, account)
+		}
+		delete(seen, account.ID)
+	}
+	for _, account := range previous.Accounts {
+		if seen[account.ID] {
+			removed = append(removed, account)
+		}
+	}
+	return added, removed
+}
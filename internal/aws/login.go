@@ -0,0 +1,175 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	oidctypes "github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+const (
+	// oidcClientName is the client name registered with IAM Identity Center
+	oidcClientName = "cloudctx"
+
+	// deviceGrantType is the OAuth grant type for device authorization
+	deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// refreshGrantType is the OAuth grant type for refreshing a token
+	refreshGrantType = "refresh_token"
+
+	// defaultPollInterval is used when the service doesn't return an interval
+	defaultPollInterval = 5 * time.Second
+)
+
+// openBrowser opens a URL in the user's browser (replaced in tests)
+var openBrowser = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// newOIDCClient creates an SSO OIDC client for the configured SSO region
+func (p *Provider) newOIDCClient(ctx context.Context) (*ssooidc.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(p.ssoRegion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return ssooidc.NewFromConfig(cfg, func(o *ssooidc.Options) {
+		if p.oidcEndpoint != "" {
+			o.BaseEndpoint = aws.String(p.oidcEndpoint)
+		}
+	}), nil
+}
+
+// deviceLogin runs the OIDC device authorization flow and caches the token:
+// register a public client, start device authorization, let the user approve
+// it in the browser, then poll CreateToken until the token is issued.
+func (p *Provider) deviceLogin(ctx context.Context) error {
+	client, err := p.newOIDCClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	registration, err := client.RegisterClient(ctx, &ssooidc.RegisterClientInput{
+		ClientName: aws.String(oidcClientName),
+		ClientType: aws.String("public"),
+		Scopes:     []string{"sso:account:access"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register SSO client: %w", err)
+	}
+
+	authz, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     registration.ClientId,
+		ClientSecret: registration.ClientSecret,
+		StartUrl:     aws.String(p.ssoStartURL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start device authorization: %w", err)
+	}
+
+	verificationURL := aws.ToString(authz.VerificationUriComplete)
+	if verificationURL == "" {
+		verificationURL = aws.ToString(authz.VerificationUri)
+	}
+
+	fmt.Fprintf(os.Stderr, "If the browser does not open, visit:\n\n  %s\n\n", verificationURL)
+	fmt.Fprintf(os.Stderr, "and confirm the code: %s\n\n", aws.ToString(authz.UserCode))
+	_ = openBrowser(verificationURL)
+
+	interval := time.Duration(authz.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	deadline := time.Now().Add(time.Duration(authz.ExpiresIn) * time.Second)
+
+	for {
+		token, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     registration.ClientId,
+			ClientSecret: registration.ClientSecret,
+			DeviceCode:   authz.DeviceCode,
+			GrantType:    aws.String(deviceGrantType),
+		})
+		if err == nil {
+			now := time.Now()
+			return p.writeSSOToken(&ssoToken{
+				StartURL:              p.ssoStartURL,
+				Region:                p.ssoRegion,
+				AccessToken:           aws.ToString(token.AccessToken),
+				ExpiresAt:             formatCacheTime(now.Add(time.Duration(token.ExpiresIn) * time.Second)),
+				ClientID:              aws.ToString(registration.ClientId),
+				ClientSecret:          aws.ToString(registration.ClientSecret),
+				RegistrationExpiresAt: formatCacheTime(time.Unix(registration.ClientSecretExpiresAt, 0)),
+				RefreshToken:          aws.ToString(token.RefreshToken),
+			})
+		}
+
+		var pending *oidctypes.AuthorizationPendingException
+		var slowDown *oidctypes.SlowDownException
+		switch {
+		case errors.As(err, &pending):
+			// User hasn't approved yet
+		case errors.As(err, &slowDown):
+			interval += defaultPollInterval
+		default:
+			return fmt.Errorf("failed to create SSO token: %w", err)
+		}
+
+		if authz.ExpiresIn > 0 && time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("device authorization expired before it was approved")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// refreshSSOToken exchanges the cached refresh token for a new access token
+// and writes the updated token back to the cache
+func (p *Provider) refreshSSOToken(ctx context.Context, token *ssoToken) (*ssoToken, error) {
+	client, err := p.newOIDCClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+		ClientId:     aws.String(token.ClientID),
+		ClientSecret: aws.String(token.ClientSecret),
+		GrantType:    aws.String(refreshGrantType),
+		RefreshToken: aws.String(token.RefreshToken),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh SSO token: %w", err)
+	}
+
+	refreshed := *token
+	refreshed.AccessToken = aws.ToString(output.AccessToken)
+	refreshed.ExpiresAt = formatCacheTime(time.Now().Add(time.Duration(output.ExpiresIn) * time.Second))
+	if output.RefreshToken != nil {
+		refreshed.RefreshToken = aws.ToString(output.RefreshToken)
+	}
+
+	if err := p.writeSSOToken(&refreshed); err != nil {
+		return nil, err
+	}
+	return &refreshed, nil
+}
@@ -0,0 +1,112 @@
+package aws
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// fakeOIDC is a minimal SSO OIDC endpoint for the device authorization flow
+func fakeOIDC(t *testing.T, pendingPolls int) *httptest.Server {
+	t.Helper()
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/client/register", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"clientId":              "client-id",
+			"clientSecret":          "client-secret",
+			"clientIdIssuedAt":      1700000000,
+			"clientSecretExpiresAt": 1900000000,
+		})
+	})
+	mux.HandleFunc("/device_authorization", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"deviceCode":              "device-code",
+			"userCode":                "ABCD-EFGH",
+			"verificationUri":         "https://device.sso.example.com/",
+			"verificationUriComplete": "https://device.sso.example.com/?user_code=ABCD-EFGH",
+			"expiresIn":               600,
+			"interval":                1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["grantType"] == refreshGrantType {
+			if body["refreshToken"] != "refresh-token" || body["clientId"] != "client-id" {
+				t.Errorf("unexpected refresh request: %v", body)
+			}
+			writeJSON(w, map[string]any{
+				"accessToken": "refreshed-token",
+				"tokenType":   "Bearer",
+				"expiresIn":   3600,
+			})
+			return
+		}
+		if body["deviceCode"] != "device-code" || body["grantType"] != deviceGrantType {
+			t.Errorf("unexpected token request: %v", body)
+		}
+		if polls < pendingPolls {
+			polls++
+			w.Header().Set("X-Amzn-ErrorType", "AuthorizationPendingException")
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]any{"error": "authorization_pending"})
+			return
+		}
+		writeJSON(w, map[string]any{
+			"accessToken":  "access-token",
+			"refreshToken": "refresh-token",
+			"tokenType":    "Bearer",
+			"expiresIn":    3600,
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestLoginWritesTokenCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := fakeOIDC(t, 1)
+
+	var opened string
+	orig := openBrowser
+	t.Cleanup(func() { openBrowser = orig })
+	openBrowser = func(url string) error {
+		opened = url
+		return nil
+	}
+
+	p := NewProvider("https://example.awsapps.com/start", "us-east-1", "us-east-1")
+	p.oidcEndpoint = srv.URL
+
+	if err := p.Login(); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	if opened != "https://device.sso.example.com/?user_code=ABCD-EFGH" {
+		t.Errorf("opened %q", opened)
+	}
+
+	data, err := os.ReadFile(p.ssoCachePath(p.sessionName))
+	if err != nil {
+		t.Fatalf("reading cache: %v", err)
+	}
+	var token ssoToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		t.Fatalf("decoding cache: %v", err)
+	}
+	if token.AccessToken != "access-token" || token.RefreshToken != "refresh-token" {
+		t.Errorf("unexpected token: %+v", token)
+	}
+	if token.StartURL != "https://example.awsapps.com/start" || token.ClientID != "client-id" {
+		t.Errorf("unexpected token: %+v", token)
+	}
+}
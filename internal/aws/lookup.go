@@ -0,0 +1,76 @@
+package aws
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/devops-chris/cloudctx/internal/provider"
+)
+
+// accountIDPattern matches a 12-digit AWS account ID
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// ssoRolePrefix starts the names of the IAM roles IAM Identity Center
+// creates for permission sets: AWSReservedSSO_<PermissionSet>_<hash>
+const ssoRolePrefix = "AWSReservedSSO_"
+
+// AccountQuery identifies an account, and optionally a role, from an
+// account ID or an ARN pasted from a log line or error message
+type AccountQuery struct {
+	AccountID string
+
+	// Role is the role or permission set named by the ARN, if any
+	Role string
+}
+
+// ParseAccountQuery parses a 12-digit account ID or an ARN. It reports false
+// for anything else, such as a profile name.
+func ParseAccountQuery(s string) (AccountQuery, bool) {
+	s = strings.TrimSpace(s)
+	if accountIDPattern.MatchString(s) {
+		return AccountQuery{AccountID: s}, true
+	}
+
+	// arn:partition:service:region:account-id:resource
+	parts := strings.SplitN(s, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || !accountIDPattern.MatchString(parts[4]) {
+		return AccountQuery{}, false
+	}
+	return AccountQuery{AccountID: parts[4], Role: arnRole(parts[2], parts[5])}, true
+}
+
+// arnRole returns the role named by an IAM role or STS assumed-role ARN
+// resource, with IAM Identity Center roles mapped back to their permission set
+func arnRole(service, resource string) string {
+	var role string
+	switch {
+	case service == "iam" && strings.HasPrefix(resource, "role/"):
+		// role/path/Name
+		resource = strings.TrimPrefix(resource, "role/")
+		role = resource[strings.LastIndex(resource, "/")+1:]
+	case service == "sts" && strings.HasPrefix(resource, "assumed-role/"):
+		// assumed-role/Name/session
+		role, _, _ = strings.Cut(strings.TrimPrefix(resource, "assumed-role/"), "/")
+	default:
+		return ""
+	}
+
+	if strings.HasPrefix(role, ssoRolePrefix) {
+		permissionSet := strings.TrimPrefix(role, ssoRolePrefix)
+		if i := strings.LastIndex(permissionSet, "_"); i > 0 {
+			permissionSet = permissionSet[:i]
+		}
+		return permissionSet
+	}
+	return role
+}
+
+// MatchesAccount reports whether a profile grants access to the account
+func (q AccountQuery) MatchesAccount(ctx provider.Context) bool {
+	return ctx.AccountID == q.AccountID
+}
+
+// MatchesRole reports whether a profile uses the role named by the ARN
+func (q AccountQuery) MatchesRole(ctx provider.Context) bool {
+	return q.Role != "" && q.MatchesAccount(ctx) && strings.EqualFold(ctx.Role, q.Role)
+}
@@ -0,0 +1,27 @@
+package aws
+
+import "testing"
+
+func TestParseAccountQuery(t *testing.T) {
+	tests := map[string]AccountQuery{
+		"123456789012": {AccountID: "123456789012"},
+		"arn:aws:iam::123456789012:role/deploy/Deploy":                                                             {AccountID: "123456789012", Role: "Deploy"},
+		"arn:aws:sts::123456789012:assumed-role/ReadOnly/jane":                                                     {AccountID: "123456789012", Role: "ReadOnly"},
+		"arn:aws:sts::123456789012:assumed-role/AWSReservedSSO_Admin_Access_0123456789abcdef/jane@example.com":     {AccountID: "123456789012", Role: "Admin_Access"},
+		"arn:aws:iam::123456789012:role/aws-reserved/sso.amazonaws.com/eu-west-1/AWSReservedSSO_ReadOnly_0123abcd": {AccountID: "123456789012", Role: "ReadOnly"},
+		"arn:aws-us-gov:iam::123456789012:user/jane":                                                               {AccountID: "123456789012"},
+		"arn:aws:lambda:eu-west-1:123456789012:function:api":                                                       {AccountID: "123456789012"},
+	}
+	for input, want := range tests {
+		got, ok := ParseAccountQuery(input)
+		if !ok || got != want {
+			t.Errorf("ParseAccountQuery(%s) = %+v, %v; want %+v", input, got, ok, want)
+		}
+	}
+
+	for _, input := range []string{"prod:admin", "12345", "arn:aws:s3:::bucket", "1234567890123"} {
+		if got, ok := ParseAccountQuery(input); ok {
+			t.Errorf("ParseAccountQuery(%s) = %+v; want no match", input, got)
+		}
+	}
+}
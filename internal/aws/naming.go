@@ -0,0 +1,229 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// DefaultProfileNameTemplate reproduces the original "account-name:role" naming
+const DefaultProfileNameTemplate = `{{.AccountName | sanitize}}:{{.RoleName | lower}}`
+
+// ProfileNameData is the data available to profile name templates
+type ProfileNameData struct {
+	AccountName string
+	AccountID   string
+	RoleName    string
+	Email       string
+}
+
+// sampleProfileNameData is used to validate templates before a sync
+var sampleProfileNameData = ProfileNameData{
+	AccountName: "Example Account",
+	AccountID:   "123456789012",
+	RoleName:    "AdministratorAccess",
+	Email:       "aws-example@example.com",
+}
+
+// profileNameFuncs are the helpers available in profile name templates
+var profileNameFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"trunc":      truncate,
+	"abbrev":     abbreviate,
+	"sanitize":   sanitizeProfileName,
+}
+
+// ParseProfileNameTemplate parses a profile name template and checks that it
+// renders a valid profile name for sample data. An empty template uses
+// DefaultProfileNameTemplate.
+func ParseProfileNameTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultProfileNameTemplate
+	}
+
+	tmpl, err := template.New("profile_name").Funcs(profileNameFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile name template: %w", err)
+	}
+
+	if _, err := renderProfileName(tmpl, sampleProfileNameData); err != nil {
+		return nil, fmt.Errorf("invalid profile name template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderProfileName executes a profile name template and validates the result.
+// Characters that can't appear in an INI section name are stripped from the
+// data first, so odd account names don't fail the sync.
+func renderProfileName(tmpl *template.Template, data ProfileNameData) (string, error) {
+	data = ProfileNameData{
+		AccountName: stripUnsafe(data.AccountName),
+		AccountID:   stripUnsafe(data.AccountID),
+		RoleName:    stripUnsafe(data.RoleName),
+		Email:       stripUnsafe(data.Email),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	name := buf.String()
+	if err := validateProfileName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// validateProfileName rejects names that can't be written as an INI section
+// header "[profile <name>]" and read back unchanged
+func validateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name is empty")
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("profile name %q has leading or trailing whitespace", name)
+	}
+	if strings.ContainsAny(name, "[]\r\n") {
+		return fmt.Errorf("profile name %q contains brackets or line breaks", name)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("profile name %q contains control characters", name)
+		}
+	}
+	if name == "default" {
+		return fmt.Errorf("profile name %q is reserved", name)
+	}
+	return nil
+}
+
+// sanitizeProfileName lowercases a value, transliterates accented letters,
+// replaces whitespace with dashes and drops characters that are unsafe in
+// INI section names or awkward in shells
+func sanitizeProfileName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if ascii, ok := transliterations[r]; ok {
+			b.WriteString(ascii)
+			continue
+		}
+		switch {
+		case unicode.IsSpace(r):
+			b.WriteRune('-')
+		case strings.ContainsRune("[];#=\"'`$\\", r), unicode.IsControl(r):
+			// drop
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// stripUnsafe removes characters that would break an INI section header
+func stripUnsafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '[' || r == ']' || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// transliterations maps common accented letters to ASCII
+var transliterations = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "ae", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "oe", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "ue", 'ý': "y", 'ÿ': "y", 'ß': "ss",
+}
+
+// ProfileCollision is a profile name several account/role pairs mapped to
+type ProfileCollision struct {
+	Name string
+
+	// Profiles are the unique names given to each colliding profile
+	Profiles []string
+}
+
+// disambiguateProfiles gives every profile a unique name. When several
+// profiles map to the same name, all of them get an account ID suffix (plus
+// the role when one account collides with itself), so no profile name
+// silently switches to a different account between syncs.
+func disambiguateProfiles(profiles []ProfilePlan) []ProfileCollision {
+	byName := make(map[string][]int)
+	var names []string
+	for i, profile := range profiles {
+		if _, seen := byName[profile.Name]; !seen {
+			names = append(names, profile.Name)
+		}
+		byName[profile.Name] = append(byName[profile.Name], i)
+	}
+
+	taken := make(map[string]bool)
+	for _, profile := range profiles {
+		taken[profile.Name] = true
+	}
+
+	var collisions []ProfileCollision
+	for _, name := range names {
+		indexes := byName[name]
+		if len(indexes) < 2 {
+			continue
+		}
+
+		perAccount := make(map[string]int)
+		for _, i := range indexes {
+			perAccount[profiles[i].AccountID]++
+		}
+
+		collision := ProfileCollision{Name: name}
+		for _, i := range indexes {
+			unique := name + "-" + profiles[i].AccountID
+			if perAccount[profiles[i].AccountID] > 1 {
+				unique += "-" + sanitizeProfileName(profiles[i].RoleName)
+			}
+			for n := 2; taken[unique]; n++ {
+				unique = fmt.Sprintf("%s-%s-%d", name, profiles[i].AccountID, n)
+			}
+			taken[unique] = true
+
+			profiles[i].Name = unique
+			collision.Profiles = append(collision.Profiles, unique)
+		}
+		collisions = append(collisions, collision)
+	}
+	return collisions
+}
+
+// abbreviate returns the lowercase initials of the words in s, splitting on
+// case changes and separators: "AdministratorAccess" -> "aa", "read-only" -> "ro"
+func abbreviate(s string) string {
+	var b strings.Builder
+	prev := ' '
+	for _, r := range s {
+		isStart := (unicode.IsLetter(r) || unicode.IsDigit(r)) &&
+			(!unicode.IsLetter(prev) && !unicode.IsDigit(prev) || unicode.IsUpper(r) && unicode.IsLower(prev))
+		if isStart {
+			b.WriteRune(unicode.ToLower(r))
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+// truncate shortens s to at most n characters
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,97 @@
+package aws
+
+import "testing"
+
+func TestProfileNameTemplates(t *testing.T) {
+	data := ProfileNameData{
+		AccountName: "Data Platform",
+		AccountID:   "123456789012",
+		RoleName:    "AdministratorAccess",
+		Email:       "data@example.com",
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"", "data-platform:administratoraccess"},
+		{"{{.RoleName}}@{{.AccountID}}", "AdministratorAccess@123456789012"},
+		{"{{.AccountName | sanitize}}-{{.RoleName | abbrev}}", "data-platform-aa"},
+		{"{{.Email | trimSuffix \"@example.com\"}}/{{.RoleName | lower | trunc 5}}", "data/admin"},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := ParseProfileNameTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.template, err)
+		}
+		got, err := renderProfileName(tmpl, data)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.template, err)
+		}
+		if got != tt.want {
+			t.Errorf("%q rendered %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestProfileNameTemplateRejectsInvalidNames(t *testing.T) {
+	for _, text := range []string{
+		"{{.AccountName",            // parse error
+		"{{.Account}}",              // unknown field
+		"[{{.RoleName}}]",           // brackets break the section header
+		" {{.RoleName}}",            // leading whitespace is trimmed by INI parsers
+		"{{.RoleName}}\n{{.Email}}", // line break
+		"default",                   // reserved
+	} {
+		if _, err := ParseProfileNameTemplate(text); err == nil {
+			t.Errorf("expected %q to be rejected", text)
+		}
+	}
+}
+
+func TestAbbreviate(t *testing.T) {
+	tests := map[string]string{
+		"AdministratorAccess": "aa",
+		"ReadOnlyAccess":      "roa",
+		"power-user":          "pu",
+		"Billing":             "b",
+	}
+	for in, want := range tests {
+		if got := abbreviate(in); got != want {
+			t.Errorf("abbreviate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDisambiguateProfiles(t *testing.T) {
+	tmpl, err := ParseProfileNameTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var profiles []ProfilePlan
+	for _, p := range []struct{ account, id, role string }{
+		{"Data Platform", "222222222222", "Admin"},
+		{"data-platform", "111111111111", "Admin"},
+		{"Café [EU]", "333333333333", "Admin"},
+	} {
+		name, err := renderProfileName(tmpl, ProfileNameData{AccountName: p.account, AccountID: p.id, RoleName: p.role})
+		if err != nil {
+			t.Fatal(err)
+		}
+		profiles = append(profiles, ProfilePlan{Name: name, AccountID: p.id, RoleName: p.role})
+	}
+
+	collisions := disambiguateProfiles(profiles)
+	if len(collisions) != 1 || collisions[0].Name != "data-platform:admin" {
+		t.Fatalf("collisions = %+v", collisions)
+	}
+
+	want := []string{"data-platform:admin-222222222222", "data-platform:admin-111111111111", "cafe-eu:admin"}
+	for i, profile := range profiles {
+		if profile.Name != want[i] {
+			t.Errorf("profile %d = %q, want %q", i, profile.Name, want[i])
+		}
+	}
+}
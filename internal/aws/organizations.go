@@ -0,0 +1,122 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
+)
+
+// DefaultOrganizationRoleName is the role AWS Organizations creates in
+// member accounts it provisions
+const DefaultOrganizationRoleName = "OrganizationAccountAccessRole"
+
+// organizationsInstanceName names Organizations sync in messages and history
+const organizationsInstanceName = "organizations"
+
+// WithOrganizations switches Sync from IAM Identity Center to AWS
+// Organizations: member accounts are listed with the credentials of
+// sourceProfile, and each gets a profile assuming roleName (default
+// OrganizationAccountAccessRole) from sourceProfile. Profile names are
+// prefixed with profilePrefix.
+func (p *Provider) WithOrganizations(sourceProfile, roleName, profilePrefix string) *Provider {
+	if roleName == "" {
+		roleName = DefaultOrganizationRoleName
+	}
+	p.instanceName = organizationsInstanceName
+	p.profilePrefix = profilePrefix
+	p.orgSourceProfile = sourceProfile
+	p.orgRoleName = roleName
+	return p
+}
+
+// Organizations reports whether the provider syncs from AWS Organizations
+func (p *Provider) Organizations() bool {
+	return p.orgSourceProfile != ""
+}
+
+// newOrganizationsClient creates an Organizations client using the source
+// profile's credentials, read from the same files Sync writes to.
+// Organizations is a global service served from us-east-1.
+func (p *Provider) newOrganizationsClient(ctx context.Context) (*organizations.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigFiles([]string{p.awsConfigPath()}),
+		config.WithSharedCredentialsFiles([]string{p.awsCredentialsPath()}),
+		config.WithSharedConfigProfile(p.orgSourceProfile),
+		config.WithRegion("us-east-1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config for profile %s: %w", p.orgSourceProfile, err)
+	}
+
+	return organizations.NewFromConfig(cfg, func(o *organizations.Options) {
+		if p.orgEndpoint != "" {
+			o.BaseEndpoint = aws.String(p.orgEndpoint)
+		}
+	}), nil
+}
+
+// listOrganizationAccountRoles lists the active member accounts of the
+// organization. Every account the filter lets through gets the configured
+// role; the management account is skipped since the role doesn't exist there.
+func (p *Provider) listOrganizationAccountRoles(ctx context.Context, filter *syncFilter) ([]ssotypes.AccountInfo, []accountRoles, error) {
+	client, err := p.newOrganizationsClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	org, err := client.DescribeOrganization(ctx, &organizations.DescribeOrganizationInput{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to describe organization: %w", err)
+	}
+	managementAccountID := aws.ToString(org.Organization.MasterAccountId)
+
+	var allAccounts []ssotypes.AccountInfo
+	var results []accountRoles
+	paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list organization accounts: %w", err)
+		}
+
+		for _, account := range page.Accounts {
+			if account.Status != orgtypes.AccountStatusActive || aws.ToString(account.Id) == managementAccountID {
+				continue
+			}
+
+			info := ssotypes.AccountInfo{
+				AccountId:    account.Id,
+				AccountName:  account.Name,
+				EmailAddress: account.Email,
+			}
+			allAccounts = append(allAccounts, info)
+
+			if filter.skipsAccount(aws.ToString(account.Name), aws.ToString(account.Id)) {
+				continue
+			}
+			results = append(results, accountRoles{
+				account:   info,
+				roles:     []ssotypes.RoleInfo{{AccountId: account.Id, RoleName: aws.String(p.orgRoleName)}},
+				partition: arnPartition(aws.ToString(account.Arn)),
+			})
+		}
+	}
+
+	return allAccounts, results, nil
+}
+
+// arnPartition returns the partition of an ARN ("aws", "aws-cn", ...),
+// defaulting to "aws"
+func arnPartition(arn string) string {
+	parts := strings.SplitN(arn, ":", 3)
+	if len(parts) < 3 || parts[0] != "arn" || parts[1] == "" {
+		return "aws"
+	}
+	return parts[1]
+}
@@ -0,0 +1,126 @@
+package aws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+// fakeOrganizations is a minimal AWS Organizations JSON endpoint
+func fakeOrganizations(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "AKIDMGMT") {
+			t.Errorf("request not signed with the source profile: %q", r.Header.Get("Authorization"))
+		}
+
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "AWSOrganizationsV20161128.DescribeOrganization":
+			writeJSON(w, map[string]any{
+				"Organization": map[string]any{
+					"Id":              "o-example",
+					"Arn":             "arn:aws:organizations::100000000000:organization/o-example",
+					"MasterAccountId": "100000000000",
+				},
+			})
+		case "AWSOrganizationsV20161128.ListAccounts":
+			account := func(id, name, status string) map[string]any {
+				return map[string]any{
+					"Id":     id,
+					"Name":   name,
+					"Email":  "aws-" + strings.ToLower(name) + "@example.com",
+					"Arn":    "arn:aws:organizations::100000000000:account/o-example/" + id,
+					"Status": status,
+				}
+			}
+			writeJSON(w, map[string]any{"Accounts": []any{
+				account("100000000000", "Management", "ACTIVE"),
+				account("111111111111", "Prod", "ACTIVE"),
+				account("222222222222", "Dev", "ACTIVE"),
+				account("333333333333", "Closed", "SUSPENDED"),
+			}})
+		default:
+			t.Errorf("unexpected operation %q", target)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOrganizationsSync(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{"AWS_PROFILE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_CONFIG_FILE", "AWS_SHARED_CREDENTIALS_FILE"} {
+		t.Setenv(env, "") // restored after the test
+		os.Unsetenv(env)
+	}
+
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	creds := "[mgmt]\naws_access_key_id = AKIDMGMT\naws_secret_access_key = secret\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewProvider("", "us-east-1", "eu-west-1").WithOrganizations("mgmt", "", "")
+	p.orgEndpoint = fakeOrganizations(t).URL
+
+	existing := "[profile old:organizationaccountaccessrole]\ncloudctx_managed = true\ncloudctx_organizations = mgmt\nrole_arn = arn:aws:iam::999999999999:role/OrganizationAccountAccessRole\nsource_profile = mgmt\n\n" +
+		"[profile sso:admin]\ncloudctx_managed = true\nsso_session = cloudctx-cli\nsso_account_id = 444444444444\n"
+	if err := os.WriteFile(p.awsConfigPath(), []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := p.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Accounts) != 2 || result.Profiles != 2 || result.Instance != "organizations" {
+		t.Errorf("result = %+v", result)
+	}
+
+	cfg, err := ini.Load(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	section, err := cfg.GetSection("profile prod:organizationaccountaccessrole")
+	if err != nil {
+		t.Fatal("prod profile not written")
+	}
+	if got := section.Key("role_arn").String(); got != "arn:aws:iam::111111111111:role/OrganizationAccountAccessRole" {
+		t.Errorf("role_arn = %q", got)
+	}
+	if got := section.Key("source_profile").String(); got != "mgmt" {
+		t.Errorf("source_profile = %q", got)
+	}
+	if _, err := cfg.GetSection("profile old:organizationaccountaccessrole"); err == nil {
+		t.Error("stale Organizations profile was not removed")
+	}
+	if _, err := cfg.GetSection("profile sso:admin"); err != nil {
+		t.Error("SSO-managed profile was removed by Organizations sync")
+	}
+	if _, err := cfg.GetSection("sso-session cloudctx-cli"); err == nil {
+		t.Error("Organizations sync wrote an sso-session")
+	}
+
+	contexts, err := p.ListContexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ctx := range contexts {
+		if ctx.Name == "dev:organizationaccountaccessrole" {
+			if ctx.AccountID != "222222222222" || ctx.AccountName != "Dev" || ctx.Role != "OrganizationAccountAccessRole" {
+				t.Errorf("context = %+v", ctx)
+			}
+			return
+		}
+	}
+	t.Error("dev profile not listed")
+}
@@ -0,0 +1,49 @@
+package aws
+
+import "gopkg.in/ini.v1"
+
+// Profile types, by how a profile gets its credentials
+const (
+	ProfileTypeSSO         = "sso"          // IAM Identity Center (sso_session or legacy sso_start_url)
+	ProfileTypeAssumeRole  = "assume-role"  // role_arn with source_profile or credential_source
+	ProfileTypeStaticKeys  = "static-keys"  // aws_access_key_id/aws_secret_access_key
+	ProfileTypeProcess     = "process"      // credential_process
+	ProfileTypeWebIdentity = "web-identity" // role_arn with web_identity_token_file
+	ProfileTypeOther       = "other"        // none of the above, e.g. only a region
+)
+
+// profileType classifies a profile from its ~/.aws/config section and its
+// ~/.aws/credentials section (either may be nil). Settings in the config
+// file take precedence, as they do for the AWS CLI.
+func profileType(configSection, credsSection *ini.Section) string {
+	for _, section := range []*ini.Section{configSection, credsSection} {
+		if section == nil {
+			continue
+		}
+		switch {
+		case section.HasKey("role_arn") && section.HasKey("web_identity_token_file"):
+			return ProfileTypeWebIdentity
+		case section.HasKey("role_arn"):
+			return ProfileTypeAssumeRole
+		case section.HasKey("sso_session"), section.HasKey("sso_start_url"):
+			return ProfileTypeSSO
+		case section.HasKey("credential_process"):
+			return ProfileTypeProcess
+		case section.HasKey("aws_access_key_id"):
+			return ProfileTypeStaticKeys
+		}
+	}
+	return ProfileTypeOther
+}
+
+// credentialsSection returns a profile's section in ~/.aws/credentials, or nil
+func credentialsSection(awsCreds *ini.File, name string) *ini.Section {
+	if awsCreds == nil {
+		return nil
+	}
+	section, err := awsCreds.GetSection(name)
+	if err != nil {
+		return nil
+	}
+	return section
+}
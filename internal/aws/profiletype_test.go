@@ -0,0 +1,79 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListContextsProfileTypes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	config := `[profile synced]
+cloudctx_managed = true
+sso_session = cloudctx-cli
+sso_account_id = 111111111111
+sso_role_name = Admin
+
+[profile deploy]
+role_arn = arn:aws:iam::222222222222:role/Deploy
+source_profile = keys
+
+[profile ci]
+role_arn = arn:aws:iam::333333333333:role/CI
+web_identity_token_file = /var/run/token
+
+[profile vault]
+credential_process = vault-aws-creds
+
+[profile keys]
+region = eu-west-1
+
+[profile regional]
+region = us-west-2
+`
+	creds := `[keys]
+aws_access_key_id = AKID
+aws_secret_access_key = secret
+
+[creds-only]
+aws_access_key_id = AKID2
+aws_secret_access_key = secret2
+`
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewProvider("", "us-east-1", "us-east-1")
+	contexts, err := p.ListContexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct{ profileType, accountID, role string }{
+		"synced":     {ProfileTypeSSO, "111111111111", "Admin"},
+		"deploy":     {ProfileTypeAssumeRole, "222222222222", "Deploy"},
+		"ci":         {ProfileTypeWebIdentity, "333333333333", "CI"},
+		"vault":      {ProfileTypeProcess, "", ""},
+		"keys":       {ProfileTypeStaticKeys, "", ""},
+		"regional":   {ProfileTypeOther, "", ""},
+		"creds-only": {ProfileTypeStaticKeys, "", ""},
+	}
+	if len(contexts) != len(want) {
+		t.Fatalf("got %d contexts, want %d", len(contexts), len(want))
+	}
+	for _, ctx := range contexts {
+		w := want[ctx.Name]
+		if ctx.Type != w.profileType || ctx.AccountID != w.accountID || ctx.Role != w.role {
+			t.Errorf("%s: type=%q account=%q role=%q, want %q %q %q",
+				ctx.Name, ctx.Type, ctx.AccountID, ctx.Role, w.profileType, w.accountID, w.role)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/devops-chris/cloudctx/internal/cache"
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"gopkg.in/ini.v1"
+)
+
+// promptCacheName is the cache of profile metadata used by
+// QuickCurrentContext and CachedContexts
+const promptCacheName = "aws_profiles"
+
+// profileCache is the cached profile metadata, rebuilt when ~/.aws/config
+// or ~/.aws/credentials change
+type profileCache struct {
+	// Current is the profile marked current in [default]
+	Current  string                      `json:"current,omitempty"`
+	Profiles map[string]provider.Context `json:"profiles"`
+}
+
+// QuickCurrentContext returns the current profile like CurrentContext, but
+// reads profile metadata from a cache that is only rebuilt when the AWS
+// config files change. It only reads local files, for the shell prompt.
+func (p *Provider) QuickCurrentContext() (*provider.Context, error) {
+	files := []string{p.awsConfigPath(), p.awsCredentialsPath()}
+	profiles, err := cache.Load(promptCacheName, files, p.buildProfileCache)
+	if err != nil {
+		return nil, err
+	}
+
+	name := os.Getenv("AWS_PROFILE")
+	if name == "" {
+		if data, err := os.ReadFile(filepath.Join(p.stateDir(), "aws_current")); err == nil {
+			name = strings.TrimSpace(string(data))
+		}
+	}
+	if name == "" {
+		name = profiles.Current
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	ctx, ok := profiles.Profiles[name]
+	if !ok {
+		// Profile exists but not in our list
+		ctx = provider.Context{Name: name, Cloud: "aws"}
+	}
+	ctx.Active = true
+	return &ctx, nil
+}
+
+// CachedContexts returns all profiles like ListContexts, from the cache
+// QuickCurrentContext uses, for shell completion
+func (p *Provider) CachedContexts() ([]provider.Context, error) {
+	files := []string{p.awsConfigPath(), p.awsCredentialsPath()}
+	profiles, err := cache.Load(promptCacheName, files, p.buildProfileCache)
+	if err != nil {
+		return nil, err
+	}
+
+	contexts := make([]provider.Context, 0, len(profiles.Profiles))
+	for _, ctx := range profiles.Profiles {
+		contexts = append(contexts, ctx)
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
+	return contexts, nil
+}
+
+func (p *Provider) buildProfileCache() (profileCache, error) {
+	contexts, err := p.ListContexts()
+	if err != nil {
+		return profileCache{}, err
+	}
+
+	profiles := profileCache{Profiles: make(map[string]provider.Context, len(contexts))}
+	for _, ctx := range contexts {
+		ctx.Active = false
+		profiles.Profiles[ctx.Name] = ctx
+	}
+	if awsCfg, err := ini.Load(p.awsConfigPath()); err == nil {
+		if section, err := awsCfg.GetSection("default"); err == nil {
+			profiles.Current = keyValue(section, "# cloudctx_current")
+		}
+	}
+	return profiles, nil
+}
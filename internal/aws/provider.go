@@ -4,25 +4,51 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/sso"
-	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/devops-chris/cloudctx/internal/provider"
 	"gopkg.in/ini.v1"
 )
 
-// Provider implements the cloud provider interface for AWS
+// defaultSSOSessionName is the [sso-session] section cloudctx manages in
+// ~/.aws/config for the unnamed SSO instance
+const defaultSSOSessionName = "cloudctx-cli"
+
+// Provider implements the cloud provider interface for AWS. Each provider
+// syncs one IAM Identity Center instance (see WithInstance).
 type Provider struct {
 	ssoStartURL   string
 	ssoRegion     string
 	defaultRegion string
+
+	// instanceName, sessionName and profilePrefix identify the SSO instance
+	instanceName  string
+	sessionName   string
+	profilePrefix string
+
+	syncOptions SyncOptions
+
+	// orgSourceProfile and orgRoleName configure Organizations sync
+	// (see WithOrganizations)
+	orgSourceProfile string
+	orgRoleName      string
+
+	// oidcEndpoint overrides the SSO OIDC endpoint (used by tests)
+	oidcEndpoint string
+
+	// orgEndpoint overrides the Organizations endpoint (used by tests)
+	orgEndpoint string
+
+	// stsEndpoint overrides the STS endpoint (used by tests)
+	stsEndpoint string
+
+	// ssoClient overrides the SSO portal client (used by tests)
+	ssoClient ssoAPI
 }
 
 // NewProvider creates a new AWS provider
@@ -31,21 +57,46 @@ func NewProvider(ssoStartURL, ssoRegion, defaultRegion string) *Provider {
 		ssoStartURL:   ssoStartURL,
 		ssoRegion:     ssoRegion,
 		defaultRegion: defaultRegion,
+		sessionName:   defaultSSOSessionName,
 	}
 }
 
+// WithInstance names the SSO instance this provider syncs. Named instances
+// get their own [sso-session cloudctx-<name>] section, and their profile
+// names are prefixed with profilePrefix (default "<name>/").
+func (p *Provider) WithInstance(name, profilePrefix string) *Provider {
+	if name == "" {
+		return p
+	}
+	if profilePrefix == "" {
+		profilePrefix = name + "/"
+	}
+	p.instanceName = name
+	p.sessionName = "cloudctx-" + name
+	p.profilePrefix = profilePrefix
+	return p
+}
+
+// InstanceName returns the SSO instance name ("" for the default instance)
+func (p *Provider) InstanceName() string {
+	return p.instanceName
+}
+
+// WithSyncOptions sets the options used by Sync
+func (p *Provider) WithSyncOptions(opts SyncOptions) *Provider {
+	p.syncOptions = opts
+	return p
+}
+
 // Name returns the provider name
 func (p *Provider) Name() string {
 	return "aws"
 }
 
-// Login performs AWS SSO login
+// Login performs AWS SSO login using the OIDC device authorization flow.
+// The resulting token is cached in ~/.aws/sso/cache so the AWS CLI and SDKs
+// can use it without a separate 'aws sso login'.
 func (p *Provider) Login() error {
-	// Check if AWS CLI is installed
-	if _, err := exec.LookPath("aws"); err != nil {
-		return fmt.Errorf("AWS CLI not found. Please install AWS CLI v2: https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html")
-	}
-
 	if p.ssoStartURL == "" {
 		return fmt.Errorf("SSO start URL not configured. Run 'cloudctx aws init' first")
 	}
@@ -55,12 +106,7 @@ func (p *Provider) Login() error {
 		return fmt.Errorf("failed to configure SSO session: %w", err)
 	}
 
-	// Use AWS CLI for SSO login with our session
-	cmd := exec.Command("aws", "sso", "login", "--sso-session", "cloudctx-cli")
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return p.deviceLogin(context.Background())
 }
 
 // ensureSSOSession creates an SSO session in ~/.aws/config
@@ -78,7 +124,7 @@ func (p *Provider) ensureSSOSession() error {
 		awsCfg = ini.Empty()
 	}
 
-	sectionName := "sso-session cloudctx-cli"
+	sectionName := "sso-session " + p.sessionName
 	section := awsCfg.Section(sectionName)
 
 	// Clear and set SSO session settings
@@ -93,120 +139,20 @@ func (p *Provider) ensureSSOSession() error {
 	return awsCfg.SaveTo(awsConfigPath)
 }
 
-// Sync synchronizes profiles from AWS SSO
-func (p *Provider) Sync() error {
-	if p.ssoStartURL == "" {
-		return fmt.Errorf("SSO start URL not configured. Run 'cloudctx aws init' first")
-	}
-
-	// Ensure SSO session exists (profiles will reference it)
-	if err := p.ensureSSOSession(); err != nil {
-		return fmt.Errorf("failed to configure SSO session: %w", err)
-	}
-
-	ctx := context.Background()
-
-	// Get SSO access token from cache
-	accessToken, err := p.getAccessToken()
-	if err != nil {
-		return fmt.Errorf("failed to get SSO access token (try 'cloudctx aws login' first): %w", err)
-	}
-
-	// Create SSO client
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(p.ssoRegion))
-	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
-	}
-	ssoClient := sso.NewFromConfig(cfg)
-
-	// List ALL accounts (with pagination)
-	var allAccounts []ssotypes.AccountInfo
-	var accountsNextToken *string
-	for {
-		accountsOutput, err := ssoClient.ListAccounts(ctx, &sso.ListAccountsInput{
-			AccessToken: aws.String(accessToken),
-			NextToken:   accountsNextToken,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to list SSO accounts: %w", err)
-		}
-		allAccounts = append(allAccounts, accountsOutput.AccountList...)
-		if accountsOutput.NextToken == nil {
-			break
-		}
-		accountsNextToken = accountsOutput.NextToken
-	}
-
-	// Load existing AWS config
-	awsConfigPath := p.awsConfigPath()
-	awsCfg, err := ini.Load(awsConfigPath)
-	if err != nil {
-		// Create new if doesn't exist
-		awsCfg = ini.Empty()
-	}
-
-	// Remove only cloudctx-managed profiles (preserve manually created ones)
-	for _, section := range awsCfg.Sections() {
-		name := section.Name()
-		if strings.HasPrefix(name, "profile ") && section.HasKey("cloudctx_managed") {
-			awsCfg.DeleteSection(name)
-		}
-	}
-
-	// Generate profiles for each account/role (using sso_session reference)
-	profileCount := 0
-	for _, account := range allAccounts {
-		// List ALL roles for this account (with pagination)
-		var allRoles []ssotypes.RoleInfo
-		var rolesNextToken *string
-		for {
-			rolesOutput, err := ssoClient.ListAccountRoles(ctx, &sso.ListAccountRolesInput{
-				AccessToken: aws.String(accessToken),
-				AccountId:   account.AccountId,
-				NextToken:   rolesNextToken,
-			})
-			if err != nil {
-				break // Skip accounts we can't list roles for
-			}
-			allRoles = append(allRoles, rolesOutput.RoleList...)
-			if rolesOutput.NextToken == nil {
-				break
-			}
-			rolesNextToken = rolesOutput.NextToken
-		}
-
-		for _, role := range allRoles {
-			profileName := p.buildProfileName(aws.ToString(account.AccountName), aws.ToString(role.RoleName))
-			sectionName := fmt.Sprintf("profile %s", profileName)
-
-			// Delete existing section first to avoid duplicates
-			awsCfg.DeleteSection(sectionName)
-
-			section, err := awsCfg.NewSection(sectionName)
-			if err != nil {
-				continue
-			}
-
-			_, _ = section.NewKey("cloudctx_managed", "true")
-			_, _ = section.NewKey("sso_session", "cloudctx-cli")
-			_, _ = section.NewKey("sso_account_id", aws.ToString(account.AccountId))
-			_, _ = section.NewKey("sso_role_name", aws.ToString(role.RoleName))
-			_, _ = section.NewKey("region", p.defaultRegion)
-			_, _ = section.NewKey("output", "json")
-			profileCount++
-		}
-	}
-
-	// Save config
-	return awsCfg.SaveTo(awsConfigPath)
-}
-
 // ListContexts returns all AWS profiles from both ~/.aws/config and ~/.aws/credentials
 func (p *Provider) ListContexts() ([]provider.Context, error) {
 	currentProfile := os.Getenv("AWS_PROFILE")
 	profileMap := make(map[string]provider.Context) // Use map to dedupe
 
+	// Credentials file is consulted for each config profile's type
+	awsCredsPath := p.awsCredentialsPath()
+	awsCreds, err := ini.Load(awsCredsPath)
+	if err != nil {
+		awsCreds = nil
+	}
+
 	// Read from ~/.aws/config (profiles use [profile name] format)
+	sources := make(map[string]string) // source_profile of role-chaining profiles
 	awsConfigPath := p.awsConfigPath()
 	if awsCfg, err := ini.Load(awsConfigPath); err == nil {
 		for _, section := range awsCfg.Sections() {
@@ -216,21 +162,49 @@ func (p *Provider) ListContexts() ([]provider.Context, error) {
 			}
 
 			profileName := strings.TrimPrefix(name, "profile ")
+			accountName := keyValue(section, "sso_account_name")
+			accountEmail := keyValue(section, "sso_account_email")
+			if accountName == "" {
+				// Written by Organizations sync
+				accountName = keyValue(section, "cloudctx_account_name")
+				accountEmail = keyValue(section, "cloudctx_account_email")
+			}
+			role := keyValue(section, "sso_role_name")
+			if role == "" {
+				_, role = parseRoleARN(keyValue(section, "role_arn"))
+			}
+			if source := keyValue(section, "source_profile"); source != "" {
+				sources[profileName] = source
+			}
+
 			profileMap[profileName] = provider.Context{
-				Name:      profileName,
-				Cloud:     "aws",
-				AccountID: section.Key("sso_account_id").String(),
-				Role:      section.Key("sso_role_name").String(),
-				Region:    section.Key("region").String(),
-				Active:    profileName == currentProfile,
-				Managed:   section.HasKey("cloudctx_managed"),
+				Name:         profileName,
+				Cloud:        "aws",
+				AccountID:    profileAccountID(section),
+				AccountName:  accountName,
+				AccountEmail: accountEmail,
+				Role:         role,
+				Region:       keyValue(section, "region"),
+				Active:       profileName == currentProfile,
+				Managed:      section.HasKey("cloudctx_managed"),
+				Type:         profileType(section, credentialsSection(awsCreds, profileName)),
 			}
 		}
 	}
 
+	// Chained profiles show the profiles they are assumed through, and
+	// inherit the account name when they stay in the source account
+	for name := range sources {
+		ctx := profileMap[name]
+		ctx.Chain = profileChain(sources, name)
+		if source, ok := profileMap[sources[name]]; ok && ctx.AccountName == "" && source.AccountID == ctx.AccountID {
+			ctx.AccountName = source.AccountName
+		}
+		profileMap[name] = ctx
+	}
+
 	// Read from ~/.aws/credentials (profiles use [name] format, no "profile " prefix)
-	awsCredsPath := p.awsCredentialsPath()
-	if awsCreds, err := ini.Load(awsCredsPath); err == nil {
+	if awsCreds != nil {
 		for _, section := range awsCreds.Sections() {
 			name := section.Name()
 			// Skip DEFAULT section and any already in config
@@ -242,9 +216,10 @@ func (p *Provider) ListContexts() ([]provider.Context, error) {
 				profileMap[name] = provider.Context{
 					Name:    name,
 					Cloud:   "aws",
-					Region:  section.Key("region").String(),
+					Region:  keyValue(section, "region"),
 					Active:  name == currentProfile,
 					Managed: false, // Credentials file profiles are always manual
+					Type:    profileType(nil, section),
 				}
 			}
 		}
@@ -300,15 +275,20 @@ func (p *Provider) SetContext(name string) error {
 	}
 
 	if foundInConfig {
-		// Ensure SSO session exists (only needed for SSO profiles)
-		if err := p.ensureSSOSession(); err != nil {
-			return fmt.Errorf("failed to configure SSO session: %w", err)
+		// Ensure our SSO session exists (only needed for profiles using it;
+		// other instances manage their own session)
+		if p.usesSession(sourceSection) {
+			if err := p.ensureSSOSession(); err != nil {
+				return fmt.Errorf("failed to configure SSO session: %w", err)
+			}
 		}
+		// Refresh the token of whichever SSO instance the profile uses
+		p.ensureFreshProfileToken(name)
 
 		// Copy all settings from config profile to default
 		for _, key := range sourceSection.Keys() {
-			// Skip our internal marker
-			if key.Name() == "cloudctx_managed" {
+			// Skip our internal markers
+			if strings.HasPrefix(key.Name(), "cloudctx_") {
 				continue
 			}
 			_, _ = defaultConfigSection.NewKey(key.Name(), key.Value())
@@ -366,6 +346,15 @@ func (p *Provider) SetContext(name string) error {
 	return nil
 }
 
+// usesSession reports whether a profile gets its credentials from this
+// provider's SSO session, directly or through a chained role
+func (p *Provider) usesSession(section *ini.Section) bool {
+	if keyValue(section, "sso_session") == p.sessionName {
+		return true
+	}
+	return isManagedProfile(section) && keyValue(section, "cloudctx_sso_session") == p.sessionName
+}
+
 func (p *Provider) stateDir() string {
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".config", "cloudctx")
@@ -423,6 +412,14 @@ func (p *Provider) CurrentContext() (*provider.Context, error) {
 func (p *Provider) WhoAmI() (*provider.Identity, error) {
 	ctx := context.Background()
 
+	// Refresh an expired token of the current profile's SSO session before
+	// the SDK reads the cache
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	p.ensureFreshProfileToken(profile)
+
 	cfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
@@ -435,14 +432,29 @@ func (p *Provider) WhoAmI() (*provider.Identity, error) {
 	}
 
 	return &provider.Identity{
-		Cloud:     "aws",
-		AccountID: aws.ToString(output.Account),
-		UserID:    aws.ToString(output.UserId),
-		ARN:       aws.ToString(output.Arn),
-		Region:    cfg.Region,
+		Cloud:       "aws",
+		AccountID:   aws.ToString(output.Account),
+		AccountName: p.accountName(aws.ToString(output.Account)),
+		UserID:      aws.ToString(output.UserId),
+		ARN:         aws.ToString(output.Arn),
+		Region:      cfg.Region,
 	}, nil
 }
 
+// accountName looks up an account's name from the synced profiles
+func (p *Provider) accountName(accountID string) string {
+	contexts, err := p.ListContexts()
+	if err != nil {
+		return ""
+	}
+	for _, ctx := range contexts {
+		if ctx.AccountID == accountID && ctx.AccountName != "" {
+			return ctx.AccountName
+		}
+	}
+	return ""
+}
+
 // Helper functions
 
 func (p *Provider) awsConfigPath() string {
@@ -455,93 +467,12 @@ func (p *Provider) awsCredentialsPath() string {
 	return filepath.Join(home, ".aws", "credentials")
 }
 
-func (p *Provider) buildProfileName(accountName, roleName string) string {
-	// Lowercase and combine: "My Account:AdminRole"
-	name := strings.ToLower(accountName)
-	name = strings.ReplaceAll(name, " ", "-")
-	role := strings.ToLower(roleName)
-	return fmt.Sprintf("%s:%s", name, role)
-}
-
-func (p *Provider) getAccessToken() (string, error) {
-	home, _ := os.UserHomeDir()
-	cacheDir := filepath.Join(home, ".aws", "sso", "cache")
-
-	entries, err := os.ReadDir(cacheDir)
+// keyValue returns a key's value, or "" if the section doesn't have it.
+// Unlike Section.Key, it doesn't add missing keys to the section.
+func keyValue(section *ini.Section, name string) string {
+	key, err := section.GetKey(name)
 	if err != nil {
-		return "", fmt.Errorf("SSO cache not found. Run 'cloudctx aws login' first")
+		return ""
 	}
-
-	// Find the most recent cache file with accessToken
-	var newestToken string
-	var newestTime int64
-
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
-			continue
-		}
-
-		filePath := filepath.Join(cacheDir, entry.Name())
-		data, err := os.ReadFile(filePath)
-		if err != nil {
-			continue
-		}
-
-		content := string(data)
-
-		// Look for accessToken in the JSON
-		if !strings.Contains(content, `"accessToken"`) {
-			continue
-		}
-
-		// Extract token using simple string search
-		// Format is: "accessToken": "value" or "accessToken":"value"
-		marker := `"accessToken"`
-		tokenStart := strings.Index(content, marker)
-		if tokenStart == -1 {
-			continue
-		}
-
-		// Move past the marker
-		afterMarker := content[tokenStart+len(marker):]
-
-		// Find the colon and skip any whitespace
-		colonPos := strings.Index(afterMarker, ":")
-		if colonPos == -1 {
-			continue
-		}
-
-		// Move past colon and whitespace to find the opening quote
-		afterColon := strings.TrimLeft(afterMarker[colonPos+1:], " \t\n")
-		if len(afterColon) == 0 || afterColon[0] != '"' {
-			continue
-		}
-
-		// Find the closing quote
-		valueStart := 1 // skip opening quote
-		valueEnd := strings.Index(afterColon[valueStart:], `"`)
-		if valueEnd <= 0 {
-			continue
-		}
-
-		token := afterColon[valueStart : valueStart+valueEnd]
-
-		// Check file modification time - use newest
-		info, err := entry.Info()
-		if err != nil {
-			continue
-		}
-
-		if info.ModTime().Unix() > newestTime {
-			newestTime = info.ModTime().Unix()
-			newestToken = token
-		}
-	}
-
-	if newestToken == "" {
-		return "", fmt.Errorf("no valid SSO access token found. Run 'cloudctx aws login' first")
-	}
-
-	return newestToken, nil
+	return key.String()
 }
-
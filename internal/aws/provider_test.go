@@ -0,0 +1,114 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+func TestSetContextCopiesOnlyExistingKeys(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	config := "[profile manual]\nregion = eu-west-1\noutput = json\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewProvider("", "us-east-1", "us-east-1")
+	if err := p.SetContext("manual"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sso_session") {
+		t.Errorf("SetContext wrote sso_session into a profile without one:\n%s", data)
+	}
+
+	cfg, err := ini.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := cfg.Section("profile manual").KeyStrings(); len(keys) != 2 {
+		t.Errorf("[profile manual] keys = %v, want region and output", keys)
+	}
+	for _, key := range cfg.Section("default").KeyStrings() {
+		switch key {
+		case "region", "output", "# cloudctx_current":
+		default:
+			t.Errorf("unexpected key %q in [default]", key)
+		}
+	}
+}
+
+func TestSetContextRefreshesProfileInstanceToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	srv := fakeOIDC(t, 0)
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	config := `[sso-session cloudctx-acme]
+sso_start_url = https://acme.awsapps.com/start
+sso_region = us-east-1
+
+[profile acme/dev]
+sso_session = cloudctx-acme
+sso_account_id = 111111111111
+sso_role_name = Admin
+`
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	acme := NewProvider("https://acme.awsapps.com/start", "us-east-1", "us-east-1").WithInstance("acme", "")
+	err := acme.writeSSOToken(&ssoToken{
+		StartURL:              "https://acme.awsapps.com/start",
+		Region:                "us-east-1",
+		AccessToken:           "stale-token",
+		ExpiresAt:             formatCacheTime(time.Now().Add(-time.Hour)),
+		ClientID:              "client-id",
+		ClientSecret:          "client-secret",
+		RegistrationExpiresAt: formatCacheTime(time.Now().Add(24 * time.Hour)),
+		RefreshToken:          "refresh-token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default instance's provider switches to another instance's profile
+	p := NewProvider("https://example.awsapps.com/start", "us-east-1", "us-east-1")
+	p.oidcEndpoint = srv.URL
+	if err := p.SetContext("acme/dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := acme.loadSSOToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "refreshed-token" {
+		t.Errorf("acme token = %q, want refreshed-token", token.AccessToken)
+	}
+}
+
+func TestInstanceSessionNames(t *testing.T) {
+	if got := NewProvider("", "", "").WithInstance("acme", "").sessionName; got == defaultSSOSessionName {
+		t.Errorf("instance acme uses the default sso-session %s", got)
+	}
+
+	// config.ValidateInstances reserves "cli" because it would share the
+	// default instance's sso-session and profiles
+	if got := NewProvider("", "", "").WithInstance("cli", "").sessionName; got != defaultSSOSessionName {
+		t.Errorf("instance cli uses sso-session %s; update the name reserved in config.ValidateInstances", got)
+	}
+}
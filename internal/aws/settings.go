@@ -0,0 +1,103 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+)
+
+// outputFormats are the output formats the AWS CLI accepts
+var outputFormats = []string{"json", "yaml", "yaml-stream", "text", "table"}
+
+// ProfileSetting sets the region and/or output format of synced profiles
+// whose account or role matches a rule
+type ProfileSetting struct {
+	Match  FilterRule
+	Region string
+	Output string
+}
+
+// compiledSetting is a ProfileSetting with its rule compiled
+type compiledSetting struct {
+	rule   compiledRule
+	region string
+	output string
+}
+
+// profileSettings picks the region and output format for each synced profile
+type profileSettings struct {
+	settings      []compiledSetting
+	defaultRegion string
+}
+
+// newProfileSettings compiles profile settings. Profiles matching no
+// setting get defaultRegion and json output.
+func newProfileSettings(settings []ProfileSetting, defaultRegion string) (*profileSettings, error) {
+	s := &profileSettings{defaultRegion: defaultRegion}
+	for _, setting := range settings {
+		if setting.Region == "" && setting.Output == "" {
+			return nil, fmt.Errorf("profile setting for %s sets neither region nor output", describeRule(setting.Match))
+		}
+		if setting.Output != "" && !validOutput(setting.Output) {
+			return nil, fmt.Errorf("profile setting for %s: unknown output %q (use %s)",
+				describeRule(setting.Match), setting.Output, strings.Join(outputFormats, ", "))
+		}
+
+		rule, err := compileRule(setting.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile setting: %w", err)
+		}
+		s.settings = append(s.settings, compiledSetting{rule: rule, region: setting.Region, output: setting.Output})
+	}
+	return s, nil
+}
+
+// resolve returns the region and output for an account/role. For each
+// field, the first matching setting that sets it wins.
+func (s *profileSettings) resolve(accountName, accountID, roleName string) (region, output string) {
+	for _, setting := range s.settings {
+		if !setting.rule.matches(accountName, accountID, roleName) {
+			continue
+		}
+		if region == "" {
+			region = setting.region
+		}
+		if output == "" {
+			output = setting.output
+		}
+	}
+
+	if region == "" {
+		region = s.defaultRegion
+	}
+	if output == "" {
+		output = "json"
+	}
+	return region, output
+}
+
+func validOutput(output string) bool {
+	for _, format := range outputFormats {
+		if output == format {
+			return true
+		}
+	}
+	return false
+}
+
+// describeRule formats a rule for error messages
+func describeRule(rule FilterRule) string {
+	var parts []string
+	if rule.Account != "" {
+		parts = append(parts, "account="+rule.Account)
+	}
+	if rule.AccountID != "" {
+		parts = append(parts, "account_id="+rule.AccountID)
+	}
+	if rule.Role != "" {
+		parts = append(parts, "role="+rule.Role)
+	}
+	if len(parts) == 0 {
+		return "all profiles"
+	}
+	return strings.Join(parts, ",")
+}
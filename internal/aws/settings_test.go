@@ -0,0 +1,44 @@
+package aws
+
+import "testing"
+
+func TestProfileSettingsResolve(t *testing.T) {
+	s, err := newProfileSettings([]ProfileSetting{
+		{Match: FilterRule{AccountID: "111111111111"}, Region: "ap-southeast-2"},
+		{Match: FilterRule{Account: "eu-*"}, Region: "eu-west-1"},
+		{Match: FilterRule{Account: "data-*"}, Region: "us-west-2", Output: "table"},
+		{Match: FilterRule{Role: "ReadOnly*"}, Output: "text"},
+	}, "us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		account, id, role string
+		region, output    string
+	}{
+		{"eu-web", "111111111111", "Admin", "ap-southeast-2", "json"},
+		{"EU-Web", "222222222222", "ReadOnlyAccess", "eu-west-1", "text"},
+		{"data-lake", "333333333333", "ReadOnlyAccess", "us-west-2", "table"},
+		{"sandbox", "444444444444", "Admin", "us-east-1", "json"},
+	}
+	for _, tt := range tests {
+		region, output := s.resolve(tt.account, tt.id, tt.role)
+		if region != tt.region || output != tt.output {
+			t.Errorf("resolve(%s, %s, %s) = %s, %s; want %s, %s",
+				tt.account, tt.id, tt.role, region, output, tt.region, tt.output)
+		}
+	}
+}
+
+func TestProfileSettingsRejectsInvalid(t *testing.T) {
+	for _, setting := range []ProfileSetting{
+		{Match: FilterRule{Account: "prod-*"}},
+		{Match: FilterRule{Account: "prod-*"}, Output: "xml"},
+		{Match: FilterRule{Account: "/[/"}, Region: "eu-west-1"},
+	} {
+		if _, err := newProfileSettings([]ProfileSetting{setting}, "us-east-1"); err == nil {
+			t.Errorf("expected %+v to be rejected", setting)
+		}
+	}
+}
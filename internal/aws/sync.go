@@ -0,0 +1,653 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
+	"github.com/devops-chris/cloudctx/internal/provider"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	// defaultSyncConcurrency is the number of accounts whose roles are listed in parallel
+	defaultSyncConcurrency = 8
+
+	// maxThrottleRetries is how often a throttled SSO call is retried
+	maxThrottleRetries = 6
+)
+
+// throttleBaseDelay is the first backoff delay after a throttled call (reduced in tests)
+var throttleBaseDelay = 500 * time.Millisecond
+
+// SyncOptions controls how Sync enumerates SSO accounts and roles
+type SyncOptions struct {
+	// Concurrency is the number of accounts whose roles are listed in parallel
+	Concurrency int
+
+	// ProfileNameTemplate is a Go template for profile names
+	// (see ProfileNameData); empty uses DefaultProfileNameTemplate
+	ProfileNameTemplate string
+
+	// Include limits sync to accounts/roles matching at least one rule
+	Include []FilterRule
+
+	// Exclude skips accounts/roles matching any rule
+	Exclude []FilterRule
+
+	// Settings map accounts/roles to regions and output formats
+	Settings []ProfileSetting
+
+	// PreserveRegion keeps regions edited by hand in existing managed
+	// profiles, so hand edits survive a sync while mapping changes still
+	// reach the other profiles
+	PreserveRegion bool
+
+	// ChainedRoles are roles assumed on top of the synced profiles
+	ChainedRoles []ChainedRole
+
+	// CredentialProcess, if set, is the cloudctx command written into
+	// synced SSO profiles as "credential_process = <command> aws
+	// credential-process --profile <name>", for SDKs without SSO support
+	CredentialProcess string
+}
+
+// ssoAPI is the subset of the SSO portal API used by cloudctx
+type ssoAPI interface {
+	ListAccounts(ctx context.Context, params *sso.ListAccountsInput, optFns ...func(*sso.Options)) (*sso.ListAccountsOutput, error)
+	ListAccountRoles(ctx context.Context, params *sso.ListAccountRolesInput, optFns ...func(*sso.Options)) (*sso.ListAccountRolesOutput, error)
+	GetRoleCredentials(ctx context.Context, params *sso.GetRoleCredentialsInput, optFns ...func(*sso.Options)) (*sso.GetRoleCredentialsOutput, error)
+}
+
+// AccountError records an account whose roles could not be listed
+type AccountError struct {
+	AccountID   string
+	AccountName string
+	Err         error
+}
+
+// accountRoles holds the roles listed for one account
+type accountRoles struct {
+	account ssotypes.AccountInfo
+	roles   []ssotypes.RoleInfo
+	err     error
+
+	// partition is the account's ARN partition (Organizations sync only)
+	partition string
+}
+
+// SyncPlan is the set of managed profiles a sync would write
+type SyncPlan struct {
+	// Profiles are the managed profiles to write, in order
+	Profiles []ProfilePlan
+
+	// Failed lists accounts whose roles could not be listed; their
+	// existing profiles are kept
+	Failed []AccountError
+
+	// Accounts are all accounts returned by SSO or Organizations
+	Accounts []provider.SyncedAccount
+
+	// Collisions lists profile names that several account/role pairs mapped
+	// to; each was given a unique name instead
+	Collisions []ProfileCollision
+
+	// Conflicts lists planned profiles that were skipped because a profile
+	// of that name already exists and isn't managed by this sync (written
+	// by hand, or by another SSO instance or Organizations sync)
+	Conflicts []string
+
+	startedAt time.Time
+}
+
+// ProfilePlan is a managed profile a sync would write
+type ProfilePlan struct {
+	Name        string
+	AccountID   string
+	AccountName string
+	RoleName    string
+	Keys        []ProfileKey
+}
+
+// ProfileKey is a key/value pair in a profile section
+type ProfileKey struct {
+	Name  string
+	Value string
+}
+
+// Sync synchronizes profiles from AWS SSO
+func (p *Provider) Sync() (*provider.SyncResult, error) {
+	plan, err := p.PlanSync()
+	if err != nil {
+		return nil, err
+	}
+	return p.ApplySync(plan)
+}
+
+// PlanSync fetches accounts and roles from AWS SSO (or AWS Organizations,
+// see WithOrganizations) and computes the managed profiles to write, without
+// touching ~/.aws/config
+func (p *Provider) PlanSync() (*SyncPlan, error) {
+	if !p.Organizations() && p.ssoStartURL == "" {
+		return nil, fmt.Errorf("SSO start URL not configured. Run 'cloudctx aws init' first")
+	}
+
+	nameTemplate, err := ParseProfileNameTemplate(p.syncOptions.ProfileNameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("aws.profile_name_template: %w", err)
+	}
+
+	filter, err := newSyncFilter(p.syncOptions.Include, p.syncOptions.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := newProfileSettings(p.syncOptions.Settings, p.defaultRegion)
+	if err != nil {
+		return nil, fmt.Errorf("aws.profile_settings: %w", err)
+	}
+
+	chains, err := compileChainedRoles(p.syncOptions.ChainedRoles)
+	if err != nil {
+		return nil, fmt.Errorf("aws.chained_roles: %w", err)
+	}
+
+	if p.profilePrefix != "" {
+		if err := validateProfileName(p.profilePrefix + "x"); err != nil {
+			return nil, fmt.Errorf("invalid profile prefix %q for %s", p.profilePrefix, p.instanceName)
+		}
+	}
+
+	ctx := context.Background()
+	startedAt := time.Now()
+
+	var allAccounts []ssotypes.AccountInfo
+	var results []accountRoles
+	if p.Organizations() {
+		allAccounts, results, err = p.listOrganizationAccountRoles(ctx, filter)
+	} else {
+		allAccounts, results, err = p.listSSOAccountRoles(ctx, filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &SyncPlan{startedAt: startedAt}
+	for _, account := range allAccounts {
+		plan.Accounts = append(plan.Accounts, provider.SyncedAccount{
+			ID:   aws.ToString(account.AccountId),
+			Name: aws.ToString(account.AccountName),
+		})
+	}
+
+	for _, result := range results {
+		// Accounts we couldn't enumerate keep their existing profiles
+		if result.err != nil {
+			plan.Failed = append(plan.Failed, AccountError{
+				AccountID:   aws.ToString(result.account.AccountId),
+				AccountName: aws.ToString(result.account.AccountName),
+				Err:         result.err,
+			})
+			continue
+		}
+
+		// Generate profiles for each account/role
+		for _, role := range result.roles {
+			if !filter.allows(aws.ToString(result.account.AccountName), aws.ToString(result.account.AccountId), aws.ToString(role.RoleName)) {
+				continue
+			}
+
+			profileName, err := renderProfileName(nameTemplate, ProfileNameData{
+				AccountName: aws.ToString(result.account.AccountName),
+				AccountID:   aws.ToString(result.account.AccountId),
+				RoleName:    aws.ToString(role.RoleName),
+				Email:       aws.ToString(result.account.EmailAddress),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("aws.profile_name_template for account %s role %s: %w",
+					aws.ToString(result.account.AccountId), aws.ToString(role.RoleName), err)
+			}
+
+			region, output := settings.resolve(aws.ToString(result.account.AccountName),
+				aws.ToString(result.account.AccountId), aws.ToString(role.RoleName))
+
+			keys := p.profileKeys(result, aws.ToString(role.RoleName), region, output)
+
+			plan.Profiles = append(plan.Profiles, ProfilePlan{
+				Name:        p.profilePrefix + profileName,
+				AccountID:   aws.ToString(result.account.AccountId),
+				AccountName: aws.ToString(result.account.AccountName),
+				RoleName:    aws.ToString(role.RoleName),
+				Keys:        keys,
+			})
+		}
+	}
+
+	plan.Collisions = disambiguateProfiles(plan.Profiles)
+
+	// credential_process needs the final, unique profile names
+	if p.syncOptions.CredentialProcess != "" && !p.Organizations() {
+		for i := range plan.Profiles {
+			plan.Profiles[i].Keys = append(plan.Profiles[i].Keys, ProfileKey{
+				"credential_process", credentialProcessCommand(p.syncOptions.CredentialProcess, plan.Profiles[i].Name),
+			})
+		}
+	}
+
+	// Chained profiles are named after the (now unique) synced profiles
+	plan.Profiles = append(plan.Profiles, p.chainProfiles(chains, plan.Profiles)...)
+
+	if awsCfg, err := ini.Load(p.awsConfigPath()); err == nil {
+		p.dropConflicts(awsCfg, plan)
+	}
+
+	return plan, nil
+}
+
+// dropConflicts removes planned profiles whose name is taken by a profile
+// this sync doesn't own, along with chained profiles sourced from them, and
+// records them in plan.Conflicts
+func (p *Provider) dropConflicts(awsCfg *ini.File, plan *SyncPlan) {
+	dropped := make(map[string]bool)
+	profiles := plan.Profiles[:0]
+	for _, profile := range plan.Profiles {
+		if !dropped[profileKeyValue(profile, "source_profile")] && !p.conflicts(awsCfg, profile.Name) {
+			profiles = append(profiles, profile)
+			continue
+		}
+		dropped[profile.Name] = true
+		plan.Conflicts = append(plan.Conflicts, profile.Name)
+	}
+	plan.Profiles = profiles
+}
+
+// conflicts reports whether awsCfg has a profile of this name that this sync
+// doesn't own and must not replace
+func (p *Provider) conflicts(awsCfg *ini.File, name string) bool {
+	section, err := awsCfg.GetSection("profile " + name)
+	return err == nil && !p.ownsProfile(section)
+}
+
+// profileKeyValue returns the value of a planned profile key, or "" if unset
+func profileKeyValue(profile ProfilePlan, name string) string {
+	for _, key := range profile.Keys {
+		if key.Name == name {
+			return key.Value
+		}
+	}
+	return ""
+}
+
+// listSSOAccountRoles lists the accounts and roles available through the
+// SSO portal. Roles are only listed for accounts the filter can let through.
+func (p *Provider) listSSOAccountRoles(ctx context.Context, filter *syncFilter) ([]ssotypes.AccountInfo, []accountRoles, error) {
+	// Get SSO access token from cache
+	accessToken, err := p.getAccessToken()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get SSO access token (try 'cloudctx aws login' first): %w", err)
+	}
+
+	ssoClient, err := p.newSSOClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allAccounts, err := p.listAccounts(ctx, ssoClient, accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var accounts []ssotypes.AccountInfo
+	for _, account := range allAccounts {
+		if !filter.skipsAccount(aws.ToString(account.AccountName), aws.ToString(account.AccountId)) {
+			accounts = append(accounts, account)
+		}
+	}
+
+	return allAccounts, p.listRoles(ctx, ssoClient, accessToken, accounts), nil
+}
+
+// profileKeys returns the keys of a synced profile. SSO profiles reference
+// the instance's sso_session; Organizations profiles assume a role from the
+// source profile. Account name and email are informational; the AWS CLI
+// ignores them.
+func (p *Provider) profileKeys(result accountRoles, roleName, region, output string) []ProfileKey {
+	accountID := aws.ToString(result.account.AccountId)
+	name := accountMetadata(result.account.AccountName)
+	email := accountMetadata(result.account.EmailAddress)
+
+	keys := []ProfileKey{{"cloudctx_managed", "true"}}
+	if p.Organizations() {
+		keys = append(keys,
+			ProfileKey{"cloudctx_organizations", p.orgSourceProfile},
+			ProfileKey{"role_arn", fmt.Sprintf("arn:%s:iam::%s:role/%s", result.partition, accountID, roleName)},
+			ProfileKey{"source_profile", p.orgSourceProfile},
+		)
+		if name != "" {
+			keys = append(keys, ProfileKey{"cloudctx_account_name", name})
+		}
+		if email != "" {
+			keys = append(keys, ProfileKey{"cloudctx_account_email", email})
+		}
+	} else {
+		keys = append(keys,
+			ProfileKey{"sso_session", p.sessionName},
+			ProfileKey{"sso_account_id", accountID},
+		)
+		if name != "" {
+			keys = append(keys, ProfileKey{"sso_account_name", name})
+		}
+		if email != "" {
+			keys = append(keys, ProfileKey{"sso_account_email", email})
+		}
+		keys = append(keys, ProfileKey{"sso_role_name", roleName})
+	}
+	return append(keys, ProfileKey{"region", region}, ProfileKey{"output", output})
+}
+
+// ApplySync writes a sync plan to ~/.aws/config and records the result in
+// the sync history. Accounts that failed to list are reported as skipped.
+func (p *Provider) ApplySync(plan *SyncPlan) (*provider.SyncResult, error) {
+	// Ensure SSO session exists (profiles will reference it)
+	if !p.Organizations() {
+		if err := p.ensureSSOSession(); err != nil {
+			return nil, fmt.Errorf("failed to configure SSO session: %w", err)
+		}
+	}
+
+	// Load existing AWS config
+	awsConfigPath := p.awsConfigPath()
+	awsCfg, err := ini.Load(awsConfigPath)
+	if err != nil {
+		// Create new if doesn't exist
+		awsCfg = ini.Empty()
+	}
+
+	p.applyPlan(awsCfg, plan)
+
+	// Save config
+	if err := awsCfg.SaveTo(awsConfigPath); err != nil {
+		return nil, err
+	}
+
+	result := &provider.SyncResult{
+		Cloud:    "aws",
+		Instance: p.instanceName,
+		Time:     plan.startedAt,
+		Duration: time.Since(plan.startedAt),
+		Accounts: plan.Accounts,
+		Profiles: len(plan.Profiles),
+	}
+	for _, failed := range plan.Failed {
+		result.Skipped = append(result.Skipped, provider.SkippedAccount{
+			ID:     failed.AccountID,
+			Name:   failed.AccountName,
+			Reason: failed.Err.Error(),
+		})
+	}
+
+	// History is informational; a failure to record it doesn't fail the sync
+	_ = p.appendSyncHistory(result)
+
+	return result, nil
+}
+
+// syncedRegionKey records the region sync wrote into a profile, so
+// PreserveRegion can tell hand edits from regions written by earlier syncs
+const syncedRegionKey = "cloudctx_region"
+
+// applyPlan replaces the managed profiles in awsCfg with the planned ones
+func (p *Provider) applyPlan(awsCfg *ini.File, plan *SyncPlan) {
+	// Remember hand-edited regions before the managed sections are replaced:
+	// those that differ from the region sync last wrote (cloudctx_region).
+	// Profiles synced before PreserveRegion was enabled have no record, so
+	// their region is sync's own and gets replaced.
+	editedRegions := make(map[string]string)
+	if p.syncOptions.PreserveRegion {
+		for _, section := range awsCfg.Sections() {
+			region := keyValue(section, "region")
+			synced := keyValue(section, syncedRegionKey)
+			if p.ownsProfile(section) && region != "" && synced != "" && region != synced {
+				editedRegions[section.Name()] = region
+			}
+		}
+	}
+
+	// Remove only this instance's cloudctx-managed profiles (preserve manually
+	// created ones and those of other SSO instances)
+	for _, section := range awsCfg.Sections() {
+		if p.ownsProfile(section) && !plan.keeps(awsCfg, section) {
+			awsCfg.DeleteSection(section.Name())
+		}
+	}
+
+	for _, profile := range plan.Profiles {
+		sectionName := fmt.Sprintf("profile %s", profile.Name)
+
+		// Never replace a profile this sync doesn't own, even if it appeared
+		// after the plan was made
+		if p.conflicts(awsCfg, profile.Name) {
+			continue
+		}
+
+		// Delete existing section first to avoid duplicates
+		awsCfg.DeleteSection(sectionName)
+
+		section, err := awsCfg.NewSection(sectionName)
+		if err != nil {
+			continue
+		}
+		for _, key := range profile.Keys {
+			if key.Name != "region" || !p.syncOptions.PreserveRegion {
+				_, _ = section.NewKey(key.Name, key.Value)
+				continue
+			}
+			region := key.Value
+			if edited, ok := editedRegions[sectionName]; ok {
+				region = edited
+			}
+			_, _ = section.NewKey("region", region)
+			_, _ = section.NewKey(syncedRegionKey, key.Value)
+		}
+	}
+}
+
+// keeps reports whether an existing managed section belongs to an account
+// that failed to sync and should be left untouched. Chained profiles belong
+// to the account of their synced source profile, not of their role_arn.
+func (plan *SyncPlan) keeps(awsCfg *ini.File, section *ini.Section) bool {
+	accountID := profileAccountID(section)
+	if source := keyValue(section, "source_profile"); source != "" {
+		if sourceSection, err := awsCfg.GetSection("profile " + source); err == nil && isManagedProfile(sourceSection) {
+			accountID = profileAccountID(sourceSection)
+		}
+	}
+	for _, failed := range plan.Failed {
+		if failed.AccountID == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// accountMetadata makes an account name or email safe to store as a single
+// line INI value
+func accountMetadata(value *string) string {
+	line := strings.Join(strings.Fields(aws.ToString(value)), " ")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, line)
+}
+
+// isManagedProfile reports whether a config section is a profile written by Sync
+func isManagedProfile(section *ini.Section) bool {
+	return strings.HasPrefix(section.Name(), "profile ") && section.HasKey("cloudctx_managed")
+}
+
+// ownsProfile reports whether a managed profile was written by this
+// provider's SSO instance or Organizations sync
+func (p *Provider) ownsProfile(section *ini.Section) bool {
+	return isManagedProfile(section) && profileOwner(section) == p.owner()
+}
+
+// owner identifies the profiles this provider manages: its SSO session, or
+// "organizations:<source profile>" for Organizations sync
+func (p *Provider) owner() string {
+	if p.Organizations() {
+		return "organizations:" + p.orgSourceProfile
+	}
+	return p.sessionName
+}
+
+// ownerKeys mark a chained profile with the provider that manages it
+func (p *Provider) ownerKeys() []ProfileKey {
+	if p.Organizations() {
+		return []ProfileKey{{"cloudctx_organizations", p.orgSourceProfile}}
+	}
+	return []ProfileKey{{"cloudctx_sso_session", p.sessionName}}
+}
+
+// profileOwner returns the owner (see Provider.owner) of a managed profile.
+// Chained profiles record it in cloudctx_sso_session or
+// cloudctx_organizations; profiles without any of these keys predate named
+// instances and belong to the default SSO instance.
+func profileOwner(section *ini.Section) string {
+	if session := keyValue(section, "sso_session"); session != "" {
+		return session
+	}
+	if session := keyValue(section, "cloudctx_sso_session"); session != "" {
+		return session
+	}
+	if source := keyValue(section, "cloudctx_organizations"); source != "" {
+		return "organizations:" + source
+	}
+	return defaultSSOSessionName
+}
+
+// newSSOClient creates an SSO portal client for the configured SSO region
+func (p *Provider) newSSOClient(ctx context.Context) (ssoAPI, error) {
+	if p.ssoClient != nil {
+		return p.ssoClient, nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(p.ssoRegion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return sso.NewFromConfig(cfg), nil
+}
+
+// listAccounts lists ALL accounts (with pagination)
+func (p *Provider) listAccounts(ctx context.Context, client ssoAPI, accessToken string) ([]ssotypes.AccountInfo, error) {
+	var allAccounts []ssotypes.AccountInfo
+	var nextToken *string
+	for {
+		var output *sso.ListAccountsOutput
+		err := withThrottleRetry(ctx, func() error {
+			var err error
+			output, err = client.ListAccounts(ctx, &sso.ListAccountsInput{
+				AccessToken: aws.String(accessToken),
+				NextToken:   nextToken,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list SSO accounts: %w", err)
+		}
+		allAccounts = append(allAccounts, output.AccountList...)
+		if output.NextToken == nil {
+			return allAccounts, nil
+		}
+		nextToken = output.NextToken
+	}
+}
+
+// listRoles lists the roles of every account using a bounded worker pool.
+// Results are returned in the same order as accounts.
+func (p *Provider) listRoles(ctx context.Context, client ssoAPI, accessToken string, accounts []ssotypes.AccountInfo) []accountRoles {
+	workers := p.syncOptions.Concurrency
+	if workers <= 0 {
+		workers = defaultSyncConcurrency
+	}
+
+	results := make([]accountRoles, len(accounts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				roles, err := p.listAccountRoles(ctx, client, accessToken, aws.ToString(accounts[i].AccountId))
+				results[i] = accountRoles{account: accounts[i], roles: roles, err: err}
+			}
+		}()
+	}
+
+	for i := range accounts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// listAccountRoles lists ALL roles for an account (with pagination)
+func (p *Provider) listAccountRoles(ctx context.Context, client ssoAPI, accessToken, accountID string) ([]ssotypes.RoleInfo, error) {
+	var allRoles []ssotypes.RoleInfo
+	var nextToken *string
+	for {
+		var output *sso.ListAccountRolesOutput
+		err := withThrottleRetry(ctx, func() error {
+			var err error
+			output, err = client.ListAccountRoles(ctx, &sso.ListAccountRolesInput{
+				AccessToken: aws.String(accessToken),
+				AccountId:   aws.String(accountID),
+				NextToken:   nextToken,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		allRoles = append(allRoles, output.RoleList...)
+		if output.NextToken == nil {
+			return allRoles, nil
+		}
+		nextToken = output.NextToken
+	}
+}
+
+// withThrottleRetry calls fn, retrying with exponential backoff and jitter
+// while the SSO API responds with TooManyRequestsException
+func withThrottleRetry(ctx context.Context, fn func() error) error {
+	delay := throttleBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		var throttled *ssotypes.TooManyRequestsException
+		if err == nil || !errors.As(err, &throttled) || attempt == maxThrottleRetries {
+			return err
+		}
+
+		jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay + jitter):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,471 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
+	"gopkg.in/ini.v1"
+)
+
+// fakeSSO is an in-memory SSO portal API
+type fakeSSO struct {
+	mu        sync.Mutex
+	accounts  []ssotypes.AccountInfo
+	roles     map[string][]string
+	throttle  map[string]int   // number of throttled calls per account
+	failures  map[string]error // permanent errors per account
+	callCount map[string]int
+}
+
+func (f *fakeSSO) ListAccounts(ctx context.Context, params *sso.ListAccountsInput, optFns ...func(*sso.Options)) (*sso.ListAccountsOutput, error) {
+	return &sso.ListAccountsOutput{AccountList: f.accounts}, nil
+}
+
+func (f *fakeSSO) ListAccountRoles(ctx context.Context, params *sso.ListAccountRolesInput, optFns ...func(*sso.Options)) (*sso.ListAccountRolesOutput, error) {
+	accountID := aws.ToString(params.AccountId)
+
+	f.mu.Lock()
+	f.callCount[accountID]++
+	calls := f.callCount[accountID]
+	f.mu.Unlock()
+
+	if err := f.failures[accountID]; err != nil {
+		return nil, err
+	}
+	if calls <= f.throttle[accountID] {
+		return nil, &ssotypes.TooManyRequestsException{Message: aws.String("slow down")}
+	}
+
+	var roles []ssotypes.RoleInfo
+	for _, role := range f.roles[accountID] {
+		roles = append(roles, ssotypes.RoleInfo{AccountId: params.AccountId, RoleName: aws.String(role)})
+	}
+	return &sso.ListAccountRolesOutput{RoleList: roles}, nil
+}
+
+func (f *fakeSSO) GetRoleCredentials(ctx context.Context, params *sso.GetRoleCredentialsInput, optFns ...func(*sso.Options)) (*sso.GetRoleCredentialsOutput, error) {
+	f.mu.Lock()
+	f.callCount["credentials:"+aws.ToString(params.AccountId)]++
+	f.mu.Unlock()
+
+	if aws.ToString(params.AccessToken) != "tok" {
+		return nil, &ssotypes.UnauthorizedException{Message: aws.String("invalid token")}
+	}
+	return &sso.GetRoleCredentialsOutput{RoleCredentials: &ssotypes.RoleCredentials{
+		AccessKeyId:     aws.String("ASIA" + aws.ToString(params.AccountId)),
+		SecretAccessKey: aws.String("secret-" + aws.ToString(params.RoleName)),
+		SessionToken:    aws.String("session"),
+		Expiration:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli(),
+	}}, nil
+}
+
+// setupSyncTest points HOME at a temp dir with a valid cached SSO token
+func setupSyncTest(t *testing.T, client ssoAPI) *Provider {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	origDelay := throttleBaseDelay
+	throttleBaseDelay = time.Millisecond
+	t.Cleanup(func() { throttleBaseDelay = origDelay })
+
+	p := NewProvider("https://example.awsapps.com/start", "us-east-1", "eu-west-1")
+	p.ssoClient = client
+	err := p.writeSSOToken(&ssoToken{
+		StartURL:    "https://example.awsapps.com/start",
+		AccessToken: "tok",
+		ExpiresAt:   formatCacheTime(time.Now().Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func account(id, name string) ssotypes.AccountInfo {
+	return ssotypes.AccountInfo{AccountId: aws.String(id), AccountName: aws.String(name)}
+}
+
+func TestSyncRetriesThrottledAccountsAndKeepsFailedProfiles(t *testing.T) {
+	client := &fakeSSO{
+		accounts: []ssotypes.AccountInfo{
+			account("111111111111", "Prod"),
+			account("222222222222", "Dev"),
+			account("333333333333", "Broken"),
+		},
+		roles: map[string][]string{
+			"111111111111": {"Admin", "ReadOnly"},
+			"222222222222": {"Admin"},
+		},
+		throttle:  map[string]int{"111111111111": 3},
+		failures:  map[string]error{"333333333333": errors.New("boom")},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+
+	// A previously synced profile for the failing account must survive
+	existing := "[profile broken:admin]\ncloudctx_managed = true\nsso_account_id = 333333333333\nsso_role_name = Admin\n"
+	if err := os.WriteFile(p.awsConfigPath(), []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := p.Sync()
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].ID != "333333333333" || result.Skipped[0].Reason != "boom" {
+		t.Errorf("unexpected skipped accounts: %+v", result.Skipped)
+	}
+	if len(result.Accounts) != 3 || result.Profiles != 3 {
+		t.Errorf("unexpected result: %d accounts, %d profiles", len(result.Accounts), result.Profiles)
+	}
+	if client.callCount["111111111111"] != 4 {
+		t.Errorf("expected 3 throttled calls and 1 success, got %d calls", client.callCount["111111111111"])
+	}
+
+	awsCfg, err := ini.Load(filepath.Join(os.Getenv("HOME"), ".aws", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"prod:admin", "prod:readonly", "dev:admin", "broken:admin"} {
+		if _, err := awsCfg.GetSection("profile " + name); err != nil {
+			t.Errorf("missing profile %s", name)
+		}
+	}
+}
+
+func TestListRolesPreservesAccountOrder(t *testing.T) {
+	client := &fakeSSO{roles: map[string][]string{}, callCount: map[string]int{}}
+	var accounts []ssotypes.AccountInfo
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("%012d", i)
+		accounts = append(accounts, account(id, id))
+		client.roles[id] = []string{"Role" + id}
+	}
+
+	p := NewProvider("", "us-east-1", "us-east-1").WithSyncOptions(SyncOptions{Concurrency: 4})
+	results := p.listRoles(context.Background(), client, "tok", accounts)
+	for i, result := range results {
+		if aws.ToString(result.account.AccountId) != aws.ToString(accounts[i].AccountId) {
+			t.Fatalf("result %d out of order", i)
+		}
+		if len(result.roles) != 1 || aws.ToString(result.roles[0].RoleName) != "Role"+aws.ToString(accounts[i].AccountId) {
+			t.Fatalf("result %d has wrong roles: %+v", i, result.roles)
+		}
+	}
+}
+
+func TestDiffSyncDoesNotSave(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod")},
+		roles:     map[string][]string{"111111111111": {"Admin", "ReadOnly"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+
+	existing := "[profile prod:admin]\ncloudctx_managed = true\nsso_session = cloudctx-cli\nsso_account_id = 111111111111\nsso_account_name = Prod\nsso_role_name = Admin\nregion = us-east-1\noutput = json\n\n" +
+		"[profile old:admin]\ncloudctx_managed = true\nsso_account_id = 999999999999\n\n" +
+		"[profile manual]\nregion = us-east-1\n"
+	if err := os.WriteFile(p.awsConfigPath(), []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := p.PlanSync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := p.DiffSync(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diff.Added) != 1 || diff.Added[0].Name != "prod:readonly" {
+		t.Errorf("added = %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "old:admin" {
+		t.Errorf("removed = %+v", diff.Removed)
+	}
+	want := KeyChange{Key: "region", Old: "us-east-1", New: "eu-west-1"}
+	if len(diff.Changed) != 1 || len(diff.Changed[0].Keys) != 1 || diff.Changed[0].Keys[0] != want {
+		t.Errorf("changed = %+v", diff.Changed)
+	}
+
+	data, err := os.ReadFile(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != existing {
+		t.Errorf("DiffSync modified ~/.aws/config:\n%s", data)
+	}
+}
+
+func TestSyncHistoryTracksAccountChanges(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod"), account("222222222222", "Dev")},
+		roles:     map[string][]string{"111111111111": {"Admin"}, "222222222222": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	client.accounts = []ssotypes.AccountInfo{account("111111111111", "Prod"), account("333333333333", "Sandbox")}
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := p.SyncHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 history entries, got %d", len(history))
+	}
+
+	added, removed := AccountChanges(&history[0], &history[1])
+	if len(added) != 1 || added[0].ID != "333333333333" {
+		t.Errorf("added = %+v", added)
+	}
+	if len(removed) != 1 || removed[0].ID != "222222222222" {
+		t.Errorf("removed = %+v", removed)
+	}
+}
+
+func TestSyncInstancesKeepEachOthersProfiles(t *testing.T) {
+	acme := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod")},
+		roles:     map[string][]string{"111111111111": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, acme)
+	p.WithInstance("acme", "")
+
+	globex := NewProvider("https://globex.awsapps.com/start", "eu-west-1", "eu-west-1").WithInstance("globex", "gx-")
+	globex.ssoClient = &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("222222222222", "Prod")},
+		roles:     map[string][]string{"222222222222": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	for _, provider := range []*Provider{p, globex} {
+		err := provider.writeSSOToken(&ssoToken{
+			StartURL:    provider.ssoStartURL,
+			AccessToken: "tok",
+			ExpiresAt:   formatCacheTime(time.Now().Add(time.Hour)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Sync both, then acme again: globex's profiles must survive
+	for _, provider := range []*Provider{p, globex, p} {
+		if _, err := provider.Sync(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	awsCfg, err := ini.Load(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"profile acme/prod:admin": "cloudctx-acme",
+		"profile gx-prod:admin":   "cloudctx-globex",
+	}
+	for name, session := range want {
+		section, err := awsCfg.GetSection(name)
+		if err != nil {
+			t.Errorf("missing %s", name)
+			continue
+		}
+		if got := section.Key("sso_session").String(); got != session {
+			t.Errorf("%s uses sso_session %s, want %s", name, got, session)
+		}
+	}
+	for _, session := range []string{"sso-session cloudctx-acme", "sso-session cloudctx-globex"} {
+		if _, err := awsCfg.GetSection(session); err != nil {
+			t.Errorf("missing [%s]", session)
+		}
+	}
+}
+
+func TestSyncHistorySkipsCorruptLines(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod")},
+		roles:     map[string][]string{"111111111111": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+
+	if err := os.MkdirAll(p.stateDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := `{"cloud":"aws","profiles":1,"accounts":[{"id":"111111111111","name":"Prod"}]}
+{"cloud":"aws","prof
+`
+	if err := os.WriteFile(filepath.Join(p.stateDir(), syncHistoryFile), []byte(corrupt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := p.SyncHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected the old and the new entry, got %d", len(history))
+	}
+}
+
+func TestSyncSkipsProfilesItDoesNotOwn(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod"), account("222222222222", "Dev")},
+		roles:     map[string][]string{"111111111111": {"Admin"}, "222222222222": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+
+	// A hand-written profile and another instance's profile share planned names
+	existing := `[profile prod:admin]
+region = ap-south-1
+
+[profile dev:admin]
+cloudctx_managed = true
+sso_session = cloudctx-globex
+sso_account_id = 999999999999
+`
+	if err := os.WriteFile(p.awsConfigPath(), []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := p.PlanSync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Conflicts) != 2 || len(plan.Profiles) != 0 {
+		t.Fatalf("conflicts = %v, profiles = %+v", plan.Conflicts, plan.Profiles)
+	}
+	if _, err := p.ApplySync(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	awsCfg, err := ini.Load(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := awsCfg.Section("profile prod:admin").KeyStrings(); len(keys) != 1 || keys[0] != "region" {
+		t.Errorf("hand-written profile was replaced: %v", keys)
+	}
+	if got := awsCfg.Section("profile dev:admin").Key("sso_account_id").String(); got != "999999999999" {
+		t.Errorf("other instance's profile was replaced: sso_account_id = %s", got)
+	}
+}
+
+func TestListContextsReadsAccountMetadata(t *testing.T) {
+	prod := account("111111111111", "Prod [EU]\nWeb")
+	prod.EmailAddress = aws.String("aws-prod@example.com")
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{prod},
+		roles:     map[string][]string{"111111111111": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	contexts, err := p.ListContexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contexts) != 1 {
+		t.Fatalf("contexts = %+v", contexts)
+	}
+	if contexts[0].AccountName != "Prod [EU] Web" || contexts[0].AccountEmail != "aws-prod@example.com" {
+		t.Errorf("account metadata = %q, %q", contexts[0].AccountName, contexts[0].AccountEmail)
+	}
+	if got := p.accountName("111111111111"); got != "Prod [EU] Web" {
+		t.Errorf("accountName = %q", got)
+	}
+}
+
+func TestSyncPreservesHandEditedRegion(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod"), account("222222222222", "Dev")},
+		roles:     map[string][]string{"111111111111": {"Admin"}, "222222222222": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+	p.WithSyncOptions(SyncOptions{
+		Settings:       []ProfileSetting{{Match: FilterRule{Account: "dev"}, Region: "us-west-2"}},
+		PreserveRegion: true,
+	})
+
+	// prod was edited by hand after a sync with preserve_region; dev was
+	// synced before preserve_region was enabled
+	existing := `[profile prod:admin]
+cloudctx_managed = true
+sso_session = cloudctx-cli
+sso_account_id = 111111111111
+region = ap-south-1
+cloudctx_region = eu-west-1
+
+[profile dev:admin]
+cloudctx_managed = true
+sso_session = cloudctx-cli
+sso_account_id = 222222222222
+region = eu-west-1
+`
+	if err := os.WriteFile(p.awsConfigPath(), []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := ini.Load(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Section("profile prod:admin").Key("region").String(); got != "ap-south-1" {
+		t.Errorf("prod:admin region = %q, want hand-edited ap-south-1", got)
+	}
+	if got := cfg.Section("profile dev:admin").Key("region").String(); got != "us-west-2" {
+		t.Errorf("dev:admin region = %q, want mapped us-west-2", got)
+	}
+
+	// A changed mapping still reaches profiles whose region sync wrote
+	p.WithSyncOptions(SyncOptions{
+		Settings:       []ProfileSetting{{Match: FilterRule{Account: "dev"}, Region: "eu-central-1"}},
+		PreserveRegion: true,
+	})
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = ini.Load(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Section("profile prod:admin").Key("region").String(); got != "ap-south-1" {
+		t.Errorf("prod:admin region = %q, want hand-edited ap-south-1", got)
+	}
+	if got := cfg.Section("profile dev:admin").Key("region").String(); got != "eu-central-1" {
+		t.Errorf("dev:admin region = %q, want remapped eu-central-1", got)
+	}
+}
@@ -0,0 +1,204 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/devops-chris/cloudctx/internal/atomicfile"
+)
+
+// VerifyStatus is the outcome of verifying a profile's access
+type VerifyStatus string
+
+const (
+	// VerifyOK means GetCallerIdentity succeeded
+	VerifyOK VerifyStatus = "ok"
+
+	// VerifyExpired means the SSO token or the profile's credentials have expired
+	VerifyExpired VerifyStatus = "expired"
+
+	// VerifyAccessDenied means the credentials were rejected, e.g. a removed
+	// permission set assignment or deleted access keys
+	VerifyAccessDenied VerifyStatus = "access-denied"
+
+	// VerifyMisconfigured covers every other failure: missing keys, unknown
+	// sso-sessions, unsupported MFA roles, unreachable endpoints
+	VerifyMisconfigured VerifyStatus = "misconfigured"
+)
+
+const (
+	// verifyResultsFile stores the latest verification result per profile in the state dir
+	verifyResultsFile = "aws_verify.json"
+
+	// defaultVerifyConcurrency is the number of profiles verified in parallel
+	defaultVerifyConcurrency = 8
+
+	// verifyTimeout bounds the time spent verifying a single profile
+	verifyTimeout = 30 * time.Second
+)
+
+// VerifyResult is the outcome of verifying one profile
+type VerifyResult struct {
+	Profile   string       `json:"profile"`
+	Status    VerifyStatus `json:"status"`
+	AccountID string       `json:"account_id,omitempty"`
+	ARN       string       `json:"arn,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	CheckedAt time.Time    `json:"checked_at"`
+}
+
+// Verify resolves the credentials of each profile and calls STS
+// GetCallerIdentity with them, concurrency profiles at a time. The current
+// profile is left alone. Results are returned in the order of names and
+// stored for VerifyResults.
+func (p *Provider) Verify(names []string, concurrency int) ([]VerifyResult, error) {
+	if concurrency <= 0 {
+		concurrency = defaultVerifyConcurrency
+	}
+
+	// Refresh each expired SSO token once, not in every worker: concurrent
+	// refreshes with the same refresh token fail once it is rotated
+	refreshed := make(map[string]bool)
+	for _, name := range names {
+		session := p.profileTokenSession(name)
+		if session == nil || refreshed[session.sessionName] {
+			continue
+		}
+		refreshed[session.sessionName] = true
+		session.ensureFreshToken()
+	}
+
+	results := make([]VerifyResult, len(names))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = p.verifyProfile(names[i])
+			}
+		}()
+	}
+
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := p.saveVerifyResults(results); err != nil {
+		return results, fmt.Errorf("failed to save verification results: %w", err)
+	}
+	return results, nil
+}
+
+// verifyProfile resolves one profile and checks its credentials with STS
+func (p *Provider) verifyProfile(name string) VerifyResult {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	result := VerifyResult{Profile: name, CheckedAt: time.Now().UTC().Truncate(time.Second)}
+
+	creds, err := p.resolveCredentials(ctx, name, 0)
+	if err != nil {
+		result.Status = classifyVerifyError(err)
+		result.Error = err.Error()
+		return result
+	}
+
+	region := creds.Region
+	if region == "" {
+		region = defaultCredentialsRegion
+	}
+	client := sts.New(sts.Options{
+		Region:      region,
+		Credentials: credentials.NewStaticCredentialsProvider(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken),
+	}, func(o *sts.Options) {
+		if p.stsEndpoint != "" {
+			o.BaseEndpoint = aws.String(p.stsEndpoint)
+		}
+	})
+
+	output, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		result.Status = classifyVerifyError(err)
+		result.Error = fmt.Sprintf("failed to get caller identity: %v", err)
+		return result
+	}
+
+	result.Status = VerifyOK
+	result.AccountID = aws.ToString(output.Account)
+	result.ARN = aws.ToString(output.Arn)
+	return result
+}
+
+// classifyVerifyError maps a credential or STS error to a status
+func classifyVerifyError(err error) VerifyStatus {
+	var expired *TokenExpiredError
+	if errors.As(err, &expired) || errors.Is(err, ErrNoSSOToken) {
+		return VerifyExpired
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ExpiredToken", "ExpiredTokenException", "RequestExpired", "UnauthorizedException":
+			return VerifyExpired
+		case "AccessDenied", "AccessDeniedException", "ForbiddenException",
+			"InvalidClientTokenId", "UnrecognizedClientException":
+			return VerifyAccessDenied
+		}
+	}
+	return VerifyMisconfigured
+}
+
+// VerifyResults returns the latest stored verification result per profile
+func (p *Provider) VerifyResults() (map[string]VerifyResult, error) {
+	data, err := os.ReadFile(filepath.Join(p.stateDir(), verifyResultsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]VerifyResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string]VerifyResult{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse verification results: %w", err)
+	}
+	return results, nil
+}
+
+// saveVerifyResults merges results into the stored results, so verifying a
+// subset of profiles keeps the others' last result
+func (p *Provider) saveVerifyResults(results []VerifyResult) error {
+	stored, err := p.VerifyResults()
+	if err != nil {
+		stored = map[string]VerifyResult{}
+	}
+	for _, result := range results {
+		stored[result.Profile] = result
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	stateDir := p.stateDir()
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(filepath.Join(stateDir, verifyResultsFile), data, 0644)
+}
@@ -0,0 +1,175 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
+)
+
+// fakeCallerIdentity is a minimal STS endpoint answering GetCallerIdentity.
+// Requests signed with AKIDREVOKED are rejected as an invalid token.
+func fakeCallerIdentity(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Form.Get("Action") != "GetCallerIdentity" {
+			t.Errorf("unexpected action %q", r.Form.Get("Action"))
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		if strings.Contains(r.Header.Get("Authorization"), "AKIDREVOKED") {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <Error><Type>Sender</Type><Code>InvalidClientTokenId</Code><Message>The security token included in the request is invalid.</Message></Error>
+</ErrorResponse>`)
+			return
+		}
+		fmt.Fprint(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:sts::111111111111:assumed-role/Admin/cloudctx</Arn>
+    <UserId>AROA:cloudctx</UserId>
+    <Account>111111111111</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVerify(t *testing.T) {
+	client := &fakeSSO{
+		accounts:  []ssotypes.AccountInfo{account("111111111111", "Prod")},
+		roles:     map[string][]string{"111111111111": {"Admin"}},
+		callCount: map[string]int{},
+	}
+	p := setupSyncTest(t, client)
+	p.stsEndpoint = fakeCallerIdentity(t).URL
+
+	if _, err := p.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	config, err := os.ReadFile(p.awsConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = append(config, `
+[profile legacy]
+sso_start_url = https://other.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 222222222222
+sso_role_name = Admin
+
+[profile broken]
+sso_session = missing
+sso_account_id = 333333333333
+sso_role_name = Admin
+`...)
+	if err := os.WriteFile(p.awsConfigPath(), config, 0600); err != nil {
+		t.Fatal(err)
+	}
+	creds := "[revoked]\naws_access_key_id = AKIDREVOKED\naws_secret_access_key = secret\n"
+	if err := os.WriteFile(p.awsCredentialsPath(), []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := p.Verify([]string{"prod:admin", "legacy", "revoked", "broken"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]VerifyStatus{
+		"prod:admin": VerifyOK,
+		"legacy":     VerifyExpired,
+		"revoked":    VerifyAccessDenied,
+		"broken":     VerifyMisconfigured,
+	}
+	for _, result := range results {
+		if result.Status != want[result.Profile] {
+			t.Errorf("%s: status %s, want %s (%s)", result.Profile, result.Status, want[result.Profile], result.Error)
+		}
+	}
+	if results[0].AccountID != "111111111111" || results[0].Error != "" {
+		t.Errorf("unexpected result for prod:admin: %+v", results[0])
+	}
+
+	// A later run for a subset keeps the other profiles' results
+	if _, err := p.Verify([]string{"prod:admin"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := p.VerifyResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 4 || stored["revoked"].Status != VerifyAccessDenied {
+		t.Errorf("unexpected stored results: %+v", stored)
+	}
+}
+
+func TestVerifyRefreshesEachSessionOnce(t *testing.T) {
+	client := &fakeSSO{callCount: map[string]int{}}
+	p := setupSyncTest(t, client)
+	p.stsEndpoint = fakeCallerIdentity(t).URL
+
+	var refreshes atomic.Int32
+	oidc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/token" || body["grantType"] != refreshGrantType {
+			t.Errorf("unexpected OIDC request %s: %v", r.URL.Path, body)
+		}
+		refreshes.Add(1)
+		writeJSON(w, map[string]any{"accessToken": "tok", "tokenType": "Bearer", "expiresIn": 3600})
+	}))
+	t.Cleanup(oidc.Close)
+	p.oidcEndpoint = oidc.URL
+
+	// Profiles of another instance share its expired token
+	config := `[sso-session cloudctx-acme]
+sso_start_url = https://acme.awsapps.com/start
+sso_region = us-east-1
+`
+	var names []string
+	for _, role := range []string{"Admin", "ReadOnly", "Billing", "Audit"} {
+		names = append(names, "acme/"+role)
+		config += fmt.Sprintf("\n[profile acme/%s]\nsso_session = cloudctx-acme\nsso_account_id = 111111111111\nsso_role_name = %s\n", role, role)
+	}
+	if err := os.WriteFile(p.awsConfigPath(), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	acme := NewProvider("https://acme.awsapps.com/start", "us-east-1", "us-east-1").WithInstance("acme", "")
+	err := acme.writeSSOToken(&ssoToken{
+		StartURL:              "https://acme.awsapps.com/start",
+		Region:                "us-east-1",
+		AccessToken:           "stale-token",
+		ExpiresAt:             formatCacheTime(time.Now().Add(-time.Hour)),
+		ClientID:              "client-id",
+		ClientSecret:          "client-secret",
+		RegistrationExpiresAt: formatCacheTime(time.Now().Add(24 * time.Hour)),
+		RefreshToken:          "refresh-token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := p.Verify(names, len(names))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Status != VerifyOK {
+			t.Errorf("%s: status %s (%s)", result.Profile, result.Status, result.Error)
+		}
+	}
+	if got := refreshes.Load(); got != 1 {
+		t.Errorf("token refreshed %d times, want once", got)
+	}
+}
@@ -0,0 +1,96 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/devops-chris/cloudctx/internal/cache"
+	"github.com/devops-chris/cloudctx/internal/provider"
+)
+
+const (
+	// promptCacheName is the cache of subscriptions used by QuickCurrentContext
+	promptCacheName = "azure_subscriptions"
+
+	// contextsCacheName is the cache of 'az account list' used by CachedContexts
+	contextsCacheName = "azure_contexts"
+
+	// contextsCacheMaxAge bounds how long CachedContexts trusts 'az account list'
+	contextsCacheMaxAge = 5 * time.Minute
+)
+
+// azureProfile is the Azure CLI's azureProfile.json
+type azureProfile struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// QuickCurrentContext returns the current subscription like CurrentContext,
+// but reads the Azure CLI's azureProfile.json (cached until it changes)
+// instead of running 'az account show'. It only reads local files, for the
+// shell prompt.
+func (p *Provider) QuickCurrentContext() (*provider.Context, error) {
+	path := filepath.Join(azureConfigDir(), "azureProfile.json")
+	subscriptions, err := cache.Load(promptCacheName, []string{path}, func() ([]Subscription, error) {
+		return readAzureProfile(path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionID := os.Getenv("AZURE_SUBSCRIPTION_ID")
+	for _, sub := range subscriptions {
+		if (subscriptionID == "" && sub.IsDefault) || (subscriptionID != "" && sub.ID == subscriptionID) {
+			return &provider.Context{
+				Name:      sub.Name,
+				Cloud:     "azure",
+				AccountID: sub.ID,
+				Region:    p.defaultLocation,
+				Active:    true,
+				Managed:   true,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// CachedContexts returns the subscriptions like ListContexts, but only runs
+// 'az account list' when the cached result is a few minutes old or the Azure
+// CLI's azureProfile.json changed, for shell completion
+func (p *Provider) CachedContexts() ([]provider.Context, error) {
+	path := filepath.Join(azureConfigDir(), "azureProfile.json")
+	return cache.LoadWithin(contextsCacheName, []string{path}, contextsCacheMaxAge, p.ListContexts)
+}
+
+// readAzureProfile reads the subscriptions from azureProfile.json. A missing
+// file means not logged in.
+func readAzureProfile(path string) ([]Subscription, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The Azure CLI writes the file with a UTF-8 byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var profile azureProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return profile.Subscriptions, nil
+}
+
+// azureConfigDir returns the Azure CLI's config directory
+func azureConfigDir() string {
+	if dir := os.Getenv("AZURE_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".azure")
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"time"
 
 	"github.com/devops-chris/cloudctx/internal/provider"
 )
@@ -79,14 +80,26 @@ func (p *Provider) Login() error {
 }
 
 // Sync is a no-op for Azure (subscriptions are always fetched live)
-func (p *Provider) Sync() error {
+func (p *Provider) Sync() (*provider.SyncResult, error) {
+	start := time.Now()
+
 	// Azure doesn't need sync - subscriptions are fetched live
 	// Just verify we're logged in
-	_, err := p.ListContexts()
+	contexts, err := p.ListContexts()
 	if err != nil {
-		return fmt.Errorf("failed to list subscriptions (are you logged in?): %w", err)
+		return nil, fmt.Errorf("failed to list subscriptions (are you logged in?): %w", err)
 	}
-	return nil
+
+	result := &provider.SyncResult{
+		Cloud:    "azure",
+		Time:     start,
+		Duration: time.Since(start),
+		Profiles: len(contexts),
+	}
+	for _, ctx := range contexts {
+		result.Accounts = append(result.Accounts, provider.SyncedAccount{ID: ctx.AccountID, Name: ctx.Name})
+	}
+	return result, nil
 }
 
 // ListContexts returns all Azure subscriptions
@@ -126,28 +139,30 @@ func (p *Provider) ListContexts() ([]provider.Context, error) {
 	return contexts, nil
 }
 
-// SetContext sets the active Azure subscription
-func (p *Provider) SetContext(name string) error {
-	// Find subscription by name or ID
+// FindSubscription finds an enabled subscription by name or ID
+func (p *Provider) FindSubscription(name string) (*provider.Context, error) {
 	contexts, err := p.ListContexts()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var subscriptionID string
 	for _, ctx := range contexts {
 		if ctx.Name == name || ctx.AccountID == name {
-			subscriptionID = ctx.AccountID
-			break
+			return &ctx, nil
 		}
 	}
+	return nil, fmt.Errorf("subscription '%s' not found", name)
+}
 
-	if subscriptionID == "" {
-		return fmt.Errorf("subscription '%s' not found", name)
+// SetContext sets the active Azure subscription
+func (p *Provider) SetContext(name string) error {
+	subscription, err := p.FindSubscription(name)
+	if err != nil {
+		return err
 	}
 
 	// Set the subscription
-	cmd := exec.Command("az", "account", "set", "--subscription", subscriptionID)
+	cmd := exec.Command("az", "account", "set", "--subscription", subscription.AccountID)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to set subscription: %w", err)
 	}
@@ -161,10 +176,11 @@ func (p *Provider) SetContext(name string) error {
 	return nil
 }
 
-// CurrentContext returns the currently active Azure subscription
+// CurrentContext returns the currently active Azure subscription:
+// AZURE_SUBSCRIPTION_ID if set (e.g. by the shell integration), otherwise
+// the Azure CLI's default
 func (p *Provider) CurrentContext() (*provider.Context, error) {
-	cmd := exec.Command("az", "account", "show", "--output", "json")
-	output, err := cmd.Output()
+	output, err := p.showAccount()
 	if err != nil {
 		return nil, nil // Not logged in or no subscription set
 	}
@@ -185,8 +201,7 @@ func (p *Provider) CurrentContext() (*provider.Context, error) {
 
 // WhoAmI returns the current Azure identity
 func (p *Provider) WhoAmI() (*provider.Identity, error) {
-	cmd := exec.Command("az", "account", "show", "--output", "json")
-	output, err := cmd.Output()
+	output, err := p.showAccount()
 	if err != nil {
 		return nil, fmt.Errorf("not logged in to Azure")
 	}
@@ -208,6 +223,16 @@ func (p *Provider) WhoAmI() (*provider.Identity, error) {
 
 // Helper functions
 
+// showAccount runs 'az account show' for AZURE_SUBSCRIPTION_ID, or for the
+// default subscription when it isn't set
+func (p *Provider) showAccount() ([]byte, error) {
+	args := []string{"account", "show", "--output", "json"}
+	if subscriptionID := os.Getenv("AZURE_SUBSCRIPTION_ID"); subscriptionID != "" {
+		args = append(args, "--subscription", subscriptionID)
+	}
+	return exec.Command("az", args...).Output()
+}
+
 func (p *Provider) stateDir() string {
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".config", "cloudctx")
@@ -0,0 +1,87 @@
+// Package cache stores values derived from files, keyed on the files'
+// modification times and sizes, so hot paths such as the shell prompt can
+// skip parsing the files again until they change.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/devops-chris/cloudctx/internal/atomicfile"
+	"github.com/devops-chris/cloudctx/internal/config"
+)
+
+// entry is a cached value and the file stamps it was built from
+type entry[T any] struct {
+	Stamps  map[string]string `json:"stamps"`
+	Created time.Time         `json:"created"`
+	Value   T                 `json:"value"`
+}
+
+// Load returns the value cached under name while none of files changed,
+// otherwise it calls build and caches the result. Files that don't exist are
+// part of the key too, so creating them invalidates the cache. Failing to
+// write the cache is not an error.
+func Load[T any](name string, files []string, build func() (T, error)) (T, error) {
+	return LoadWithin(name, files, 0, build)
+}
+
+// LoadWithin is Load for values that also go stale on their own, such as
+// results of CLI calls: the cached value is rebuilt once it is older than
+// maxAge. A zero maxAge never expires.
+func LoadWithin[T any](name string, files []string, maxAge time.Duration, build func() (T, error)) (T, error) {
+	stamps := fileStamps(files)
+	path := filepath.Join(Dir(), name+".json")
+
+	if data, err := os.ReadFile(path); err == nil {
+		var cached entry[T]
+		if json.Unmarshal(data, &cached) == nil && maps.Equal(cached.Stamps, stamps) &&
+			(maxAge <= 0 || time.Since(cached.Created) < maxAge) {
+			return cached.Value, nil
+		}
+	}
+
+	value, err := build()
+	if err != nil {
+		return value, err
+	}
+	_ = write(path, entry[T]{Stamps: stamps, Created: time.Now(), Value: value})
+	return value, nil
+}
+
+// Dir returns the cache directory under the cloudctx config directory
+func Dir() string {
+	return filepath.Join(config.ConfigDir(), "cache")
+}
+
+// fileStamps returns each file's modification time and size
+func fileStamps(files []string) map[string]string {
+	stamps := make(map[string]string, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			stamps[file] = "missing"
+			continue
+		}
+		stamps[file] = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+	}
+	return stamps
+}
+
+// write stores an entry through a temp file, so concurrent prompts never
+// read a partial cache
+func write(path string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(path, data, 0600)
+}
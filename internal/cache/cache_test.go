@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadRebuildsWhenFilesChange(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	file := filepath.Join(home, "config")
+
+	builds := 0
+	build := func() (string, error) {
+		builds++
+		data, _ := os.ReadFile(file)
+		return string(data), nil
+	}
+	load := func() string {
+		t.Helper()
+		value, err := Load("test", []string{file}, build)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	// A missing file is part of the key
+	if got := load(); got != "" || builds != 1 {
+		t.Fatalf("got %q after %d build(s)", got, builds)
+	}
+	if err := os.WriteFile(file, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := load(); got != "a" || builds != 2 {
+		t.Fatalf("got %q after %d build(s), want a rebuild", got, builds)
+	}
+	if got := load(); got != "a" || builds != 2 {
+		t.Fatalf("got %q after %d build(s), want the cached value", got, builds)
+	}
+
+	if err := os.WriteFile(file, []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := load(); got != "b" || builds != 3 {
+		t.Fatalf("got %q after %d build(s), want a rebuild", got, builds)
+	}
+}
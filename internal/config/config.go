@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/spf13/viper"
 )
@@ -17,6 +19,9 @@ type Config struct {
 
 	// Azure configuration
 	Azure AzureConfig `mapstructure:"azure"`
+
+	// Prompt configures 'cloudctx prompt'
+	Prompt PromptConfig `mapstructure:"prompt"`
 }
 
 // AWSConfig holds AWS-specific configuration
@@ -29,6 +34,156 @@ type AWSConfig struct {
 
 	// DefaultRegion is the default AWS region for profiles
 	DefaultRegion string `mapstructure:"default_region"`
+
+	// Instances are additional named IAM Identity Center instances
+	// (one per AWS Organization)
+	Instances []SSOInstance `mapstructure:"instances"`
+
+	// Organizations syncs profiles from AWS Organizations instead of (or
+	// alongside) IAM Identity Center
+	Organizations OrganizationsConfig `mapstructure:"organizations"`
+
+	// SyncConcurrency is the number of accounts whose roles are fetched in parallel during sync
+	SyncConcurrency int `mapstructure:"sync_concurrency"`
+
+	// ProfileNameTemplate is a Go template for synced profile names.
+	// Fields: .AccountName, .AccountID, .RoleName, .Email
+	ProfileNameTemplate string `mapstructure:"profile_name_template"`
+
+	// Include limits sync to accounts/roles matching at least one rule
+	Include []SyncFilter `mapstructure:"include"`
+
+	// Exclude skips accounts/roles matching any rule during sync
+	Exclude []SyncFilter `mapstructure:"exclude"`
+
+	// ProfileSettings set the region and output of matching synced profiles;
+	// for each field the first matching entry wins
+	ProfileSettings []ProfileSetting `mapstructure:"profile_settings"`
+
+	// PreserveRegion keeps regions edited by hand in existing synced
+	// profiles, so hand edits survive the next sync
+	PreserveRegion bool `mapstructure:"preserve_region"`
+
+	// ChainedRoles are roles assumed on top of matching synced profiles
+	ChainedRoles []ChainedRole `mapstructure:"chained_roles"`
+
+	// CredentialProcess adds "credential_process = cloudctx aws
+	// credential-process ..." to synced profiles, for SDKs without SSO support
+	CredentialProcess bool `mapstructure:"credential_process"`
+}
+
+// SSOInstance is a named IAM Identity Center instance
+type SSOInstance struct {
+	// Name identifies the instance; its sso-session is "cloudctx-<name>"
+	Name string `mapstructure:"name"`
+
+	// SSOStartURL is the instance's SSO portal URL
+	SSOStartURL string `mapstructure:"sso_start_url"`
+
+	// SSORegion is the instance's SSO region (default: aws.sso_region)
+	SSORegion string `mapstructure:"sso_region"`
+
+	// ProfilePrefix is prepended to synced profile names (default: "<name>/")
+	ProfilePrefix string `mapstructure:"profile_prefix"`
+}
+
+// OrganizationsConfig configures profile generation from AWS Organizations
+// for IAM users that assume a role in each member account
+type OrganizationsConfig struct {
+	// SourceProfile holds the IAM user credentials in the management account
+	SourceProfile string `mapstructure:"source_profile"`
+
+	// RoleName is assumed in each member account
+	// (default: OrganizationAccountAccessRole)
+	RoleName string `mapstructure:"role_name"`
+
+	// ProfilePrefix is prepended to generated profile names
+	ProfilePrefix string `mapstructure:"profile_prefix"`
+}
+
+// SSOInstances returns all configured SSO instances. The top-level
+// sso_start_url, if set, is the unnamed default instance and comes first.
+func (c AWSConfig) SSOInstances() []SSOInstance {
+	var instances []SSOInstance
+	if c.SSOStartURL != "" {
+		instances = append(instances, SSOInstance{
+			SSOStartURL: c.SSOStartURL,
+			SSORegion:   c.SSORegion,
+		})
+	}
+	for _, instance := range c.Instances {
+		if instance.SSORegion == "" {
+			instance.SSORegion = c.SSORegion
+		}
+		instances = append(instances, instance)
+	}
+	return instances
+}
+
+// instanceNamePattern matches SSO instance names, which become part of
+// sso-session section names and profile names
+var instanceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateInstances checks that named SSO instances have unique names that
+// are safe in ~/.aws/config section and profile names. "organizations" is
+// reserved for Organizations sync (ctx aws sync --instance organizations),
+// and "cli" because its sso-session, cloudctx-cli, is the default instance's.
+func (c AWSConfig) ValidateInstances() error {
+	seen := make(map[string]bool)
+	for i, instance := range c.Instances {
+		if instance.Name == "" {
+			return fmt.Errorf("aws.instances[%d]: name is required", i)
+		}
+		if !instanceNamePattern.MatchString(instance.Name) {
+			return fmt.Errorf("aws.instances[%d]: name %q may only contain letters, digits, '-' and '_'", i, instance.Name)
+		}
+		if instance.Name == "organizations" {
+			return fmt.Errorf("aws.instances[%d]: name %q is reserved for Organizations sync", i, instance.Name)
+		}
+		if instance.Name == "cli" {
+			return fmt.Errorf("aws.instances[%d]: name %q is reserved for the default SSO instance (sso-session cloudctx-cli)", i, instance.Name)
+		}
+		if seen[instance.Name] {
+			return fmt.Errorf("aws.instances[%d]: duplicate name %q", i, instance.Name)
+		}
+		seen[instance.Name] = true
+	}
+	return nil
+}
+
+// SyncFilter matches accounts and roles during sync. Fields are globs
+// ("sandbox-*") or regular expressions wrapped in slashes ("/^sbx-\d+$/").
+type SyncFilter struct {
+	Account   string `mapstructure:"account"`
+	AccountID string `mapstructure:"account_id"`
+	Role      string `mapstructure:"role"`
+}
+
+// ProfileSetting sets the region and/or output format of synced profiles
+// matching its account, account_id and role patterns (see SyncFilter)
+type ProfileSetting struct {
+	Account   string `mapstructure:"account"`
+	AccountID string `mapstructure:"account_id"`
+	Role      string `mapstructure:"role"`
+	Region    string `mapstructure:"region"`
+	Output    string `mapstructure:"output"`
+}
+
+// ChainedRole generates "<profile>/<name>" profiles that assume RoleARN
+// from every synced profile matching its account, account_id and role patterns
+type ChainedRole struct {
+	Account   string `mapstructure:"account"`
+	AccountID string `mapstructure:"account_id"`
+	Role      string `mapstructure:"role"`
+
+	// Name is appended to the source profile name
+	Name string `mapstructure:"name"`
+
+	// RoleARN is the role to assume; {account} is the source account ID
+	RoleARN string `mapstructure:"role_arn"`
+
+	// ExternalID is passed when assuming the role, if set
+	ExternalID string `mapstructure:"external_id"`
 }
 
 // AzureConfig holds Azure-specific configuration
@@ -37,18 +192,53 @@ type AzureConfig struct {
 	DefaultLocation string `mapstructure:"default_location"`
 }
 
+// PromptConfig configures the shell prompt segment
+type PromptConfig struct {
+	// Format is a Go template for each active context.
+	// Fields: .Cloud, .Name, .AccountName, .AccountID, .Role, .Region, .Env
+	Format string `mapstructure:"format"`
+
+	// Environments tag contexts (e.g. prod, dev) to color them in the
+	// prompt; the first matching entry wins
+	Environments []EnvironmentTag `mapstructure:"environments"`
+}
+
+// EnvironmentTag tags contexts whose name, account name or account ID
+// matches one of its globs
+type EnvironmentTag struct {
+	// Tag is available to the prompt format as .Env
+	Tag string `mapstructure:"tag"`
+
+	// Match holds globs such as "*prod*" or "123456789012"
+	Match []string `mapstructure:"match"`
+
+	// Color is red, green, yellow, blue, magenta, cyan, white or gray
+	Color string `mapstructure:"color"`
+}
+
+// DefaultEnvironments are used when prompt.environments isn't configured
+var DefaultEnvironments = []EnvironmentTag{
+	{Tag: "prod", Match: []string{"*prod*", "*prd*"}, Color: "red"},
+	{Tag: "staging", Match: []string{"*stag*", "*uat*"}, Color: "yellow"},
+	{Tag: "dev", Match: []string{"*dev*", "*sandbox*", "*test*"}, Color: "green"},
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
 		DefaultCloud: "aws",
 		AWS: AWSConfig{
-			SSOStartURL:   "",
-			SSORegion:     "us-east-1",
-			DefaultRegion: "us-east-1",
+			SSOStartURL:     "",
+			SSORegion:       "us-east-1",
+			DefaultRegion:   "us-east-1",
+			SyncConcurrency: 8,
 		},
 		Azure: AzureConfig{
 			DefaultLocation: "eastus",
 		},
+		Prompt: PromptConfig{
+			Format: "{{.Cloud}}:{{.Name}}",
+		},
 	}
 }
 
@@ -63,7 +253,12 @@ func Load(configFile string) *Config {
 	v.SetDefault("aws.sso_start_url", cfg.AWS.SSOStartURL)
 	v.SetDefault("aws.sso_region", cfg.AWS.SSORegion)
 	v.SetDefault("aws.default_region", cfg.AWS.DefaultRegion)
+	v.SetDefault("aws.sync_concurrency", cfg.AWS.SyncConcurrency)
+	v.SetDefault("aws.profile_name_template", cfg.AWS.ProfileNameTemplate)
+	v.SetDefault("aws.preserve_region", cfg.AWS.PreserveRegion)
+	v.SetDefault("aws.credential_process", cfg.AWS.CredentialProcess)
 	v.SetDefault("azure.default_location", cfg.Azure.DefaultLocation)
+	v.SetDefault("prompt.format", cfg.Prompt.Format)
 
 	// Environment variables
 	v.SetEnvPrefix("CLOUDCTX")
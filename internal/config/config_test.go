@@ -0,0 +1,22 @@
+package config
+
+import "testing"
+
+func TestValidateInstances(t *testing.T) {
+	valid := AWSConfig{Instances: []SSOInstance{{Name: "acme"}, {Name: "acme_sub-2"}}}
+	if err := valid.ValidateInstances(); err != nil {
+		t.Errorf("ValidateInstances() = %v", err)
+	}
+
+	for name, instances := range map[string][]SSOInstance{
+		"empty name":      {{Name: ""}},
+		"duplicate name":  {{Name: "acme"}, {Name: "acme"}},
+		"unsafe name":     {{Name: "acme corp]"}},
+		"reserved name":   {{Name: "organizations"}},
+		"default session": {{Name: "cli"}},
+	} {
+		if err := (AWSConfig{Instances: instances}).ValidateInstances(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
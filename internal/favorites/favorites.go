@@ -0,0 +1,131 @@
+// Package favorites stores the contexts pinned by 'cloudctx fav', in
+// cloudctx's own state so that syncing profiles can't remove them
+package favorites
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/devops-chris/cloudctx/internal/atomicfile"
+	"github.com/devops-chris/cloudctx/internal/config"
+	"github.com/devops-chris/cloudctx/internal/provider"
+)
+
+// favoritesFile is the JSON file of favorites in the config dir
+const favoritesFile = "favorites.json"
+
+// Favorites maps a cloud ("aws", "azure") to its favorite context names, in
+// the order they were added
+type Favorites map[string][]string
+
+// Load returns the stored favorites
+func Load() (Favorites, error) {
+	data, err := os.ReadFile(filepath.Join(config.ConfigDir(), favoritesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return Favorites{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	favorites := Favorites{}
+	if err := json.Unmarshal(data, &favorites); err != nil {
+		return nil, fmt.Errorf("failed to parse favorites: %w", err)
+	}
+	return favorites, nil
+}
+
+// Set returns the favorite context names of a cloud. A missing or unreadable
+// favorites file means no favorites.
+func Set(cloud string) map[string]bool {
+	favorites, err := Load()
+	if err != nil {
+		return nil
+	}
+
+	set := make(map[string]bool, len(favorites[cloud]))
+	for _, name := range favorites[cloud] {
+		set[name] = true
+	}
+	return set
+}
+
+// Add adds a context to a cloud's favorites. It reports false when the
+// context already was a favorite.
+func Add(cloud, name string) (bool, error) {
+	favorites, err := Load()
+	if err != nil {
+		return false, err
+	}
+
+	for _, existing := range favorites[cloud] {
+		if existing == name {
+			return false, nil
+		}
+	}
+	favorites[cloud] = append(favorites[cloud], name)
+	return true, favorites.save()
+}
+
+// Remove removes a context from a cloud's favorites. It reports false when
+// the context was not a favorite.
+func Remove(cloud, name string) (bool, error) {
+	favorites, err := Load()
+	if err != nil {
+		return false, err
+	}
+
+	var kept []string
+	for _, existing := range favorites[cloud] {
+		if existing != name {
+			kept = append(kept, existing)
+		}
+	}
+	if len(kept) == len(favorites[cloud]) {
+		return false, nil
+	}
+
+	if len(kept) == 0 {
+		delete(favorites, cloud)
+	} else {
+		favorites[cloud] = kept
+	}
+	return true, favorites.save()
+}
+
+// Pin returns the contexts with favorites first, keeping the order within
+// favorites and within the rest
+func Pin(contexts []provider.Context, favorites map[string]bool) []provider.Context {
+	if len(favorites) == 0 {
+		return contexts
+	}
+
+	pinned := make([]provider.Context, 0, len(contexts))
+	var rest []provider.Context
+	for _, ctx := range contexts {
+		if favorites[ctx.Name] {
+			pinned = append(pinned, ctx)
+		} else {
+			rest = append(rest, ctx)
+		}
+	}
+	return append(pinned, rest...)
+}
+
+// save writes the favorites to the config dir
+func (f Favorites) save() error {
+	dir := config.ConfigDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(filepath.Join(dir, favoritesFile), data, 0644)
+}
@@ -0,0 +1,64 @@
+package favorites
+
+import (
+	"testing"
+
+	"github.com/devops-chris/cloudctx/internal/provider"
+)
+
+func TestAddRemove(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if set := Set("aws"); len(set) != 0 {
+		t.Fatalf("Set(aws) with no favorites = %v", set)
+	}
+
+	for _, name := range []string{"prod:admin", "dev:admin", "prod:admin"} {
+		if _, err := Add("aws", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Add("azure", "Sub A"); err != nil {
+		t.Fatal(err)
+	}
+
+	favorites, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := favorites["aws"]; len(got) != 2 || got[0] != "prod:admin" || got[1] != "dev:admin" {
+		t.Fatalf("aws favorites = %v, want [prod:admin dev:admin]", got)
+	}
+
+	if removed, err := Remove("aws", "prod:admin"); err != nil || !removed {
+		t.Fatalf("Remove(prod:admin) = %v, %v", removed, err)
+	}
+	if removed, err := Remove("aws", "prod:admin"); err != nil || removed {
+		t.Fatalf("second Remove(prod:admin) = %v, %v", removed, err)
+	}
+
+	set := Set("aws")
+	if len(set) != 1 || !set["dev:admin"] {
+		t.Fatalf("Set(aws) = %v, want dev:admin", set)
+	}
+	if set := Set("azure"); !set["Sub A"] {
+		t.Fatalf("Set(azure) = %v, want Sub A", set)
+	}
+}
+
+func TestPin(t *testing.T) {
+	contexts := []provider.Context{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+
+	pinned := Pin(contexts, map[string]bool{"d": true, "b": true})
+
+	var names []string
+	for _, ctx := range pinned {
+		names = append(names, ctx.Name)
+	}
+	want := []string{"b", "d", "a", "c"}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Pin() = %v, want %v", names, want)
+		}
+	}
+}
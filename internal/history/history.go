@@ -0,0 +1,142 @@
+// Package history records context switches, so cloudctx can switch back to
+// the previous context and list recent ones
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/devops-chris/cloudctx/internal/atomicfile"
+	"github.com/devops-chris/cloudctx/internal/config"
+)
+
+// historyFile is the JSON-lines file of switches in the config dir
+const historyFile = "switch_history.jsonl"
+
+// maxEntries is the number of switches kept in the history
+const maxEntries = 200
+
+// Entry is one successful context switch
+type Entry struct {
+	Time  time.Time `json:"time"`
+	Cloud string    `json:"cloud"`
+	Name  string    `json:"name"`
+}
+
+// Record appends a switch from one context of a cloud to another, keeping
+// the newest maxEntries. The context switched away from ("" if none) is
+// recorded first unless it already is the cloud's latest entry, so it becomes
+// the previous context even when it was selected outside cloudctx.
+func Record(cloud, from, to string) error {
+	entries, err := Load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if from != "" && from != to && latest(entries, cloud) != from {
+		entries = append(entries, Entry{Time: now, Cloud: cloud, Name: from})
+	}
+	entries = append(entries, Entry{Time: now, Cloud: cloud, Name: to})
+	if len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
+	}
+
+	dir := config.ConfigDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return atomicfile.WriteFile(filepath.Join(dir, historyFile), buf.Bytes(), 0644)
+}
+
+// latest returns the name of the most recent entry of a cloud, or ""
+func latest(entries []Entry, cloud string) string {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Cloud == cloud {
+			return entries[i].Name
+		}
+	}
+	return ""
+}
+
+// Load returns the recorded switches, oldest first. Lines that can't be
+// parsed (e.g. from an interrupted write by an older version) are skipped.
+func Load() ([]Entry, error) {
+	f, err := os.Open(filepath.Join(config.ConfigDir(), historyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Cloud == "" || entry.Name == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Previous returns the context of a cloud that was active before the most
+// recent switch, like 'cd -': switching to it and calling Previous again
+// returns the context switched away from. It returns nil when there is none.
+func Previous(cloud string) (*Entry, error) {
+	entries, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	current := ""
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Cloud != cloud {
+			continue
+		}
+		if current == "" {
+			current = entries[i].Name
+			continue
+		}
+		if entries[i].Name != current {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Recent returns the most recent switch to each distinct context, newest first
+func Recent() ([]Entry, error) {
+	entries, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var recent []Entry
+	seen := make(map[Entry]bool)
+	for i := len(entries) - 1; i >= 0; i-- {
+		key := Entry{Cloud: entries[i].Cloud, Name: entries[i].Name}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		recent = append(recent, entries[i])
+	}
+	return recent, nil
+}
@@ -0,0 +1,113 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/devops-chris/cloudctx/internal/config"
+)
+
+func TestPreviousAndRecent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if previous, err := Previous("aws"); err != nil || previous != nil {
+		t.Fatalf("Previous() with no history = %v, %v", previous, err)
+	}
+
+	for _, entry := range []Entry{
+		{Cloud: "aws", Name: "dev"},
+		{Cloud: "aws", Name: "prod"},
+		{Cloud: "azure", Name: "Sub A"},
+		{Cloud: "aws", Name: "prod"},
+	} {
+		if err := Record(entry.Cloud, "", entry.Name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous, err := Previous("aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous == nil || previous.Name != "dev" {
+		t.Fatalf("Previous(aws) = %+v, want dev", previous)
+	}
+
+	// Switching back makes the profile switched away from the previous one
+	if err := Record("aws", "prod", "dev"); err != nil {
+		t.Fatal(err)
+	}
+	if previous, _ := Previous("aws"); previous == nil || previous.Name != "prod" {
+		t.Fatalf("Previous(aws) = %+v, want prod", previous)
+	}
+	if previous, _ := Previous("azure"); previous != nil {
+		t.Fatalf("Previous(azure) = %+v, want none", previous)
+	}
+
+	recent, err := Recent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range recent {
+		names = append(names, entry.Cloud+":"+entry.Name)
+	}
+	want := []string{"aws:dev", "aws:prod", "azure:Sub A"}
+	if len(names) != len(want) {
+		t.Fatalf("Recent() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Recent() = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestRecordKeepsOutgoingContextAndSkipsBadLines(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := os.MkdirAll(config.ConfigDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := `{"time":"2026-01-01T00:00:00Z","cloud":"aws","name":"dev"}
+{"time":"2026-01-01T00:01:00Z","cloud":"aws","na
+{"time":"2026-01-01T00:02:00Z","cloud":"azure","name":"Sub A"}
+`
+	if err := os.WriteFile(filepath.Join(config.ConfigDir(), historyFile), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The current profile was selected outside cloudctx
+	if err := Record("aws", "staging", "prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Cloud+":"+entry.Name)
+	}
+	want := []string{"aws:dev", "azure:Sub A", "aws:staging", "aws:prod"}
+	if len(names) != len(want) {
+		t.Fatalf("entries = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("entries = %v, want %v", names, want)
+		}
+	}
+	if previous, _ := Previous("aws"); previous == nil || previous.Name != "staging" {
+		t.Fatalf("Previous(aws) = %+v, want staging", previous)
+	}
+
+	// An outgoing context that is already the latest entry isn't repeated
+	if err := Record("aws", "prod", "dev"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := Load(); len(entries) != 5 {
+		t.Errorf("got %d entries, want 5", len(entries))
+	}
+}
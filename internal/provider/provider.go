@@ -1,16 +1,21 @@
 // Package provider defines the interface for cloud providers
 package provider
 
+import "time"
+
 // Context represents a cloud context (AWS profile, Azure subscription, etc.)
 type Context struct {
-	Name        string
-	Cloud       string // "aws", "azure", "gcp"
-	AccountID   string
-	AccountName string
-	Role        string // AWS role, Azure role, etc.
-	Region      string
-	Active      bool
-	Managed     bool   // true if created/managed by cloudctx (SSO sync)
+	Name         string
+	Cloud        string // "aws", "azure", "gcp"
+	AccountID    string
+	AccountName  string
+	AccountEmail string
+	Role         string // AWS role, Azure role, etc.
+	Region       string
+	Active       bool
+	Managed      bool     // true if created/managed by cloudctx (SSO sync)
+	Type         string   // how credentials are obtained, e.g. "sso", "assume-role"
+	Chain        []string // profiles this one is assumed through, outermost first
 }
 
 // Identity represents the current authenticated identity
@@ -23,6 +28,30 @@ type Identity struct {
 	Region      string
 }
 
+// SyncResult describes the outcome of a sync
+type SyncResult struct {
+	Cloud    string           `json:"cloud"`
+	Instance string           `json:"instance,omitempty"` // e.g. the AWS SSO instance
+	Time     time.Time        `json:"time"`
+	Duration time.Duration    `json:"duration"`
+	Accounts []SyncedAccount  `json:"accounts"` // All accounts seen, including skipped ones
+	Profiles int              `json:"profiles"` // Number of contexts generated
+	Skipped  []SkippedAccount `json:"skipped,omitempty"`
+}
+
+// SyncedAccount is an account seen during a sync
+type SyncedAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SkippedAccount is an account that could not be synced
+type SkippedAccount struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
 // Provider defines the interface that all cloud providers must implement
 type Provider interface {
 	// Name returns the provider name (e.g., "aws", "azure")
@@ -32,7 +61,7 @@ type Provider interface {
 	Login() error
 
 	// Sync synchronizes available contexts from the cloud (e.g., fetch SSO accounts/roles)
-	Sync() error
+	Sync() (*SyncResult, error)
 
 	// ListContexts returns all available contexts
 	ListContexts() ([]Context, error)